@@ -1,46 +1,43 @@
 package run_consumer
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
 
 	consumer "github.com/radheem/ran-kafka-client-go/pkg/consumer"
 )
 
-func ExecuteConsumer(port string, topics []string, consumerGroup string, mongoURI string, mongoDB string, mongoCollection string ) {
-    if (port == ""){
-        return 
-    }
-    brokers := []string{"localhost:"+port}
-    
-    if len(topics) == 0 || topics[0] == "" {
-        log.Fatal("KAFKA_TOPICS environment variable is required")
-    }
-    if consumerGroup == "" {
-        consumerGroup = "default-consumer-group"
-    }
-    if mongoDB == "" {
-        mongoDB = "kafka_data"
-    }
-    if mongoCollection == "" {
-        mongoCollection = "messages"
-    }
+// ExecuteConsumer runs a consumer until SIGINT or SIGTERM is received or stop
+// is closed. Defaults are expected to have been applied by pkg/config.
+func ExecuteConsumer(config consumer.Config, stop <-chan struct{}) error {
+	if len(config.Topics) == 0 || config.Topics[0] == "" {
+		return fmt.Errorf("at least one topic is required")
+	}
+	if config.ConsumerGroup == "" {
+		return fmt.Errorf("a consumer group is required")
+	}
 
-    config := consumer.Config{
-        Brokers:         brokers,
-        Topics:          topics,
-        ConsumerGroup:   consumerGroup,
-        MongoURI:        mongoURI,
-        MongoDB:         mongoDB,
-        MongoCollection: mongoCollection,
-    }
+	c, err := consumer.NewConsumer(config)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
 
-    c, err := consumer.NewConsumer(config)
-    if err != nil {
-        log.Fatal("Failed to create consumer:", err)
-    }
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if stop != nil {
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
 
-    log.Printf("Starting consumer with config: %+v", config)
-    if err := c.Start(); err != nil {
-        log.Fatal("Failed to start consumer:", err)
-    }
-}
\ No newline at end of file
+	if err := c.Run(ctx); err != nil {
+		return fmt.Errorf("consumer failed: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/radheem/ran-kafka-client-go/pkg/consumer"
 )
@@ -29,8 +32,10 @@ func consumerMain() {
 	log.Println("Starting Kafka consumer...")
 	log.Println("Press Ctrl+C to stop")
 
-	// Start consuming messages (this blocks until interrupted)
-	if err := kafkaConsumer.Start(); err != nil {
+	// Consume messages until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := kafkaConsumer.Run(ctx); err != nil {
 		log.Fatalf("Consumer error: %v", err)
 	}
 }
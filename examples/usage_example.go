@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/radheem/ran-kafka-client-go/pkg/consumer"
@@ -128,7 +131,8 @@ func runConsumerExample() error {
 	fmt.Println("Starting consumer...")
 	fmt.Println("Press Ctrl+C to stop the consumer")
 	
-	// Start consuming messages
-	// This will block until interrupted
-	return kafkaConsumer.Start()
+	// Consume messages until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return kafkaConsumer.Run(ctx)
 }
@@ -0,0 +1,57 @@
+// Package admin wraps sarama.ClusterAdmin with the topic and consumer group
+// operations used by the ran-kafka CLI.
+package admin
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+)
+
+type Config struct {
+    Brokers  []string
+    Security kafkaconfig.Security
+    // SaramaOptions override individual sarama settings, such as the client
+    // ID or the Kafka version, after the security settings.
+    SaramaOptions []kafkaconfig.Option
+}
+
+type Admin struct {
+    config Config
+    client sarama.Client
+    admin  sarama.ClusterAdmin
+}
+
+func NewAdmin(config Config) (*Admin, error) {
+    saramaConfig := sarama.NewConfig()
+    if err := config.Security.Apply(saramaConfig); err != nil {
+        return nil, err
+    }
+    overrides := kafkaconfig.ApplyOptions(saramaConfig, config.SaramaOptions)
+    if err := kafkaconfig.ValidateOverrides(saramaConfig, overrides); err != nil {
+        return nil, fmt.Errorf("invalid admin configuration: %w", err)
+    }
+
+    client, err := sarama.NewClient(config.Brokers, saramaConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create client: %w", err)
+    }
+
+    admin, err := sarama.NewClusterAdminFromClient(client)
+    if err != nil {
+        client.Close()
+        return nil, fmt.Errorf("failed to create cluster admin: %w", err)
+    }
+
+    return &Admin{
+        config: config,
+        client: client,
+        admin:  admin,
+    }, nil
+}
+
+// Close closes the cluster admin and its client.
+func (a *Admin) Close() error {
+    return a.admin.Close()
+}
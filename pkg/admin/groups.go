@@ -0,0 +1,352 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// GroupSummary is a row of ListGroups.
+type GroupSummary struct {
+    Name         string `json:"name"`
+    ProtocolType string `json:"protocolType"`
+}
+
+// GroupMember is an active member of a consumer group.
+type GroupMember struct {
+    MemberID    string             `json:"memberId"`
+    InstanceID  string             `json:"instanceId,omitempty"`
+    ClientID    string             `json:"clientId"`
+    Host        string             `json:"host"`
+    Assignments map[string][]int32 `json:"assignments"`
+}
+
+// PartitionLag is the committed offset of a group on one partition. A
+// CommittedOffset of -1 means the group has not committed on it; its Lag is
+// then the number of messages still in the log, from the earliest offset.
+type PartitionLag struct {
+    Topic           string `json:"topic"`
+    Partition       int32  `json:"partition"`
+    CommittedOffset int64  `json:"committedOffset"`
+    LogEndOffset    int64  `json:"logEndOffset"`
+    Lag             int64  `json:"lag"`
+    MemberID        string `json:"memberId,omitempty"`
+}
+
+// GroupDescription is the result of DescribeGroup.
+type GroupDescription struct {
+    Name     string         `json:"name"`
+    State    string         `json:"state"`
+    Protocol string         `json:"protocol"`
+    Members  []GroupMember  `json:"members"`
+    Offsets  []PartitionLag `json:"offsets"`
+    TotalLag int64          `json:"totalLag"`
+}
+
+// ResetStrategy selects how ResetSpec computes the new offsets.
+type ResetStrategy int
+
+const (
+    ResetToEarliest ResetStrategy = iota
+    ResetToLatest
+    ResetToDatetime
+    ResetShiftBy
+    ResetToOffset
+)
+
+// ResetSpec describes an offset reset. Time is used by ResetToDatetime,
+// Shift by ResetShiftBy and Offset by ResetToOffset.
+type ResetSpec struct {
+    Strategy ResetStrategy
+    Time     time.Time
+    Shift    int64
+    Offset   int64
+}
+
+// OffsetReset is one partition of a reset plan.
+type OffsetReset struct {
+    Topic     string `json:"topic"`
+    Partition int32  `json:"partition"`
+    Current   int64  `json:"current"`
+    Target    int64  `json:"target"`
+}
+
+// ErrGroupActive is returned when resetting the offsets of a group that still
+// has members.
+var ErrGroupActive = errors.New("consumer group has active members")
+
+// ListGroups returns every consumer group sorted by name.
+func (a *Admin) ListGroups() ([]GroupSummary, error) {
+    groups, err := a.admin.ListConsumerGroups()
+    if err != nil {
+        return nil, fmt.Errorf("failed to list consumer groups: %w", err)
+    }
+
+    summaries := make([]GroupSummary, 0, len(groups))
+    for name, protocolType := range groups {
+        summaries = append(summaries, GroupSummary{Name: name, ProtocolType: protocolType})
+    }
+    sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+    return summaries, nil
+}
+
+// DescribeGroup returns the members of a group and its committed offset,
+// log-end offset and lag on every partition it has committed on or is
+// assigned.
+func (a *Admin) DescribeGroup(group string) (*GroupDescription, error) {
+    descriptions, err := a.admin.DescribeConsumerGroups([]string{group})
+    if err != nil {
+        return nil, fmt.Errorf("failed to describe consumer group %s: %w", group, err)
+    }
+    if len(descriptions) == 0 {
+        return nil, fmt.Errorf("consumer group %s not found", group)
+    }
+    d := descriptions[0]
+    if d.Err != sarama.ErrNoError {
+        return nil, fmt.Errorf("failed to describe consumer group %s: %w", group, d.Err)
+    }
+
+    description := &GroupDescription{
+        Name:     group,
+        State:    d.State,
+        Protocol: d.Protocol,
+    }
+
+    owners := map[string]map[int32]string{}
+    for id, m := range d.Members {
+        member := GroupMember{
+            MemberID:    id,
+            ClientID:    m.ClientId,
+            Host:        m.ClientHost,
+            Assignments: map[string][]int32{},
+        }
+        if m.GroupInstanceId != nil {
+            member.InstanceID = *m.GroupInstanceId
+        }
+        if assignment, err := m.GetMemberAssignment(); err == nil && assignment != nil {
+            member.Assignments = assignment.Topics
+            for topic, partitions := range assignment.Topics {
+                if owners[topic] == nil {
+                    owners[topic] = map[int32]string{}
+                }
+                for _, p := range partitions {
+                    owners[topic][p] = id
+                }
+            }
+        }
+        description.Members = append(description.Members, member)
+    }
+    sort.Slice(description.Members, func(i, j int) bool {
+        return description.Members[i].MemberID < description.Members[j].MemberID
+    })
+
+    committed, err := a.committedOffsets(group)
+    if err != nil {
+        return nil, err
+    }
+    // Assigned partitions without a commit are shown too.
+    for topic, partitions := range owners {
+        for p := range partitions {
+            if committed[topic] == nil {
+                committed[topic] = map[int32]int64{}
+            }
+            if _, ok := committed[topic][p]; !ok {
+                committed[topic][p] = -1
+            }
+        }
+    }
+
+    for topic, partitions := range committed {
+        for p, offset := range partitions {
+            end, err := a.client.GetOffset(topic, p, sarama.OffsetNewest)
+            if err != nil {
+                return nil, fmt.Errorf("failed to get log-end offset of %s[%d]: %w", topic, p, err)
+            }
+            start := offset
+            if start < 0 {
+                if start, err = a.client.GetOffset(topic, p, sarama.OffsetOldest); err != nil {
+                    return nil, fmt.Errorf("failed to get earliest offset of %s[%d]: %w", topic, p, err)
+                }
+            }
+            lag := end - start
+            description.TotalLag += lag
+            description.Offsets = append(description.Offsets, PartitionLag{
+                Topic:           topic,
+                Partition:       p,
+                CommittedOffset: offset,
+                LogEndOffset:    end,
+                Lag:             lag,
+                MemberID:        owners[topic][p],
+            })
+        }
+    }
+    sortPartitions(description.Offsets, func(i int) (string, int32) {
+        return description.Offsets[i].Topic, description.Offsets[i].Partition
+    })
+    return description, nil
+}
+
+// DeleteGroup deletes a consumer group without active members.
+func (a *Admin) DeleteGroup(group string) error {
+    if err := a.admin.DeleteConsumerGroup(group); err != nil {
+        return fmt.Errorf("failed to delete consumer group %s: %w", group, err)
+    }
+    return nil
+}
+
+// PlanOffsetReset computes the new offsets of a group on topics, or on every
+// topic it has committed on when topics is empty. Targets are clamped to the
+// range of available offsets; ResetShiftBy shifts partitions without a
+// committed offset from the earliest one.
+func (a *Admin) PlanOffsetReset(group string, topics []string, spec ResetSpec) ([]OffsetReset, error) {
+    committed, err := a.committedOffsets(group)
+    if err != nil {
+        return nil, err
+    }
+    if len(topics) == 0 {
+        for topic := range committed {
+            topics = append(topics, topic)
+        }
+    }
+
+    var plan []OffsetReset
+    for _, topic := range topics {
+        partitions, err := a.client.Partitions(topic)
+        if err != nil {
+            return nil, fmt.Errorf("failed to list partitions of %s: %w", topic, err)
+        }
+        for _, p := range partitions {
+            current, ok := committed[topic][p]
+            if !ok {
+                current = -1
+            }
+            target, err := a.resetTarget(topic, p, current, spec)
+            if err != nil {
+                return nil, err
+            }
+            plan = append(plan, OffsetReset{Topic: topic, Partition: p, Current: current, Target: target})
+        }
+    }
+    sortPartitions(plan, func(i int) (string, int32) { return plan[i].Topic, plan[i].Partition })
+    return plan, nil
+}
+
+func (a *Admin) resetTarget(topic string, partition int32, current int64, spec ResetSpec) (int64, error) {
+    earliest, err := a.client.GetOffset(topic, partition, sarama.OffsetOldest)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get earliest offset of %s[%d]: %w", topic, partition, err)
+    }
+    latest, err := a.client.GetOffset(topic, partition, sarama.OffsetNewest)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get latest offset of %s[%d]: %w", topic, partition, err)
+    }
+
+    var target int64
+    switch spec.Strategy {
+    case ResetToEarliest:
+        target = earliest
+    case ResetToLatest:
+        target = latest
+    case ResetToDatetime:
+        target, err = a.client.GetOffset(topic, partition, spec.Time.UnixMilli())
+        if err != nil {
+            return 0, fmt.Errorf("failed to get offset of %s[%d] at %v: %w", topic, partition, spec.Time, err)
+        }
+        // No message at or after the time
+        if target < 0 {
+            target = latest
+        }
+    case ResetShiftBy:
+        // A partition without a commit is shifted from the earliest offset.
+        from := current
+        if from < 0 {
+            from = earliest
+        }
+        target = from + spec.Shift
+    case ResetToOffset:
+        target = spec.Offset
+    default:
+        return 0, fmt.Errorf("unknown reset strategy %d", spec.Strategy)
+    }
+    return min(max(target, earliest), latest), nil
+}
+
+// ApplyOffsetReset commits a reset plan. The group must have no active
+// members, otherwise they would overwrite the new offsets.
+func (a *Admin) ApplyOffsetReset(group string, plan []OffsetReset) error {
+    descriptions, err := a.admin.DescribeConsumerGroups([]string{group})
+    if err != nil {
+        return fmt.Errorf("failed to describe consumer group %s: %w", group, err)
+    }
+    if len(descriptions) > 0 && len(descriptions[0].Members) > 0 {
+        return fmt.Errorf("cannot reset offsets of %s: %w", group, ErrGroupActive)
+    }
+
+    coordinator, err := a.client.Coordinator(group)
+    if err != nil {
+        return fmt.Errorf("failed to find coordinator of %s: %w", group, err)
+    }
+
+    req := &sarama.OffsetCommitRequest{
+        Version:                 2,
+        ConsumerGroup:           group,
+        ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+        RetentionTime:           -1,
+    }
+    for _, reset := range plan {
+        req.AddBlock(reset.Topic, reset.Partition, reset.Target, sarama.ReceiveTime, "")
+    }
+
+    resp, err := coordinator.CommitOffset(req)
+    if err != nil {
+        return fmt.Errorf("failed to commit offsets of %s: %w", group, err)
+    }
+    for topic, partitions := range resp.Errors {
+        for p, kerr := range partitions {
+            if kerr != sarama.ErrNoError {
+                return fmt.Errorf("failed to commit offset of %s on %s[%d]: %w", group, topic, p, kerr)
+            }
+        }
+    }
+    return nil
+}
+
+// committedOffsets returns the committed offset of a group on every
+// partition it has committed on.
+func (a *Admin) committedOffsets(group string) (map[string]map[int32]int64, error) {
+    resp, err := a.admin.ListConsumerGroupOffsets(group, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list offsets of %s: %w", group, err)
+    }
+    if resp.Err != sarama.ErrNoError {
+        return nil, fmt.Errorf("failed to list offsets of %s: %w", group, resp.Err)
+    }
+
+    offsets := map[string]map[int32]int64{}
+    for topic, partitions := range resp.Blocks {
+        for p, block := range partitions {
+            if block.Err != sarama.ErrNoError || block.Offset < 0 {
+                continue
+            }
+            if offsets[topic] == nil {
+                offsets[topic] = map[int32]int64{}
+            }
+            offsets[topic][p] = block.Offset
+        }
+    }
+    return offsets, nil
+}
+
+func sortPartitions[T any](rows []T, key func(int) (string, int32)) {
+    sort.Slice(rows, func(i, j int) bool {
+        ti, pi := key(i)
+        tj, pj := key(j)
+        if ti != tj {
+            return ti < tj
+        }
+        return pi < pj
+    })
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/IBM/sarama"
+)
+
+// TopicSummary is a row of ListTopics.
+type TopicSummary struct {
+    Name              string `json:"name"`
+    Partitions        int32  `json:"partitions"`
+    ReplicationFactor int16  `json:"replicationFactor"`
+}
+
+// PartitionDescription describes the replicas of one partition.
+type PartitionDescription struct {
+    ID              int32   `json:"id"`
+    Leader          int32   `json:"leader"`
+    Replicas        []int32 `json:"replicas"`
+    ISR             []int32 `json:"isr"`
+    OfflineReplicas []int32 `json:"offlineReplicas"`
+}
+
+// TopicDescription is the result of DescribeTopic. Configs only holds values
+// that differ from the broker defaults.
+type TopicDescription struct {
+    Name       string                 `json:"name"`
+    Internal   bool                   `json:"internal"`
+    Partitions []PartitionDescription `json:"partitions"`
+    Configs    map[string]string      `json:"configs"`
+}
+
+// CreateTopicOptions are the settings of a new topic. A ReplicationFactor of
+// -1 uses the broker default.
+type CreateTopicOptions struct {
+    Partitions        int32
+    ReplicationFactor int16
+    Configs           map[string]string
+    ValidateOnly      bool
+}
+
+// ListTopics returns every topic sorted by name.
+func (a *Admin) ListTopics() ([]TopicSummary, error) {
+    details, err := a.admin.ListTopics()
+    if err != nil {
+        return nil, fmt.Errorf("failed to list topics: %w", err)
+    }
+
+    topics := make([]TopicSummary, 0, len(details))
+    for name, detail := range details {
+        topics = append(topics, TopicSummary{
+            Name:              name,
+            Partitions:        detail.NumPartitions,
+            ReplicationFactor: detail.ReplicationFactor,
+        })
+    }
+    sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+    return topics, nil
+}
+
+// DescribeTopic returns the partitions and non-default configs of a topic.
+func (a *Admin) DescribeTopic(name string) (*TopicDescription, error) {
+    metadata, err := a.admin.DescribeTopics([]string{name})
+    if err != nil {
+        return nil, fmt.Errorf("failed to describe topic %s: %w", name, err)
+    }
+    if len(metadata) == 0 {
+        return nil, fmt.Errorf("topic %s not found", name)
+    }
+    if metadata[0].Err != sarama.ErrNoError {
+        return nil, fmt.Errorf("failed to describe topic %s: %w", name, metadata[0].Err)
+    }
+
+    description := &TopicDescription{
+        Name:     name,
+        Internal: metadata[0].IsInternal,
+        Configs:  map[string]string{},
+    }
+    for _, p := range metadata[0].Partitions {
+        description.Partitions = append(description.Partitions, PartitionDescription{
+            ID:              p.ID,
+            Leader:          p.Leader,
+            Replicas:        p.Replicas,
+            ISR:             p.Isr,
+            OfflineReplicas: p.OfflineReplicas,
+        })
+    }
+    sort.Slice(description.Partitions, func(i, j int) bool {
+        return description.Partitions[i].ID < description.Partitions[j].ID
+    })
+
+    entries, err := a.admin.DescribeConfig(sarama.ConfigResource{
+        Type: sarama.TopicResource,
+        Name: name,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to describe configs of topic %s: %w", name, err)
+    }
+    for _, entry := range entries {
+        if entry.Default || entry.Source == sarama.SourceDefault || entry.Source == sarama.SourceStaticBroker {
+            continue
+        }
+        value := entry.Value
+        if entry.Sensitive {
+            value = "<redacted>"
+        }
+        description.Configs[entry.Name] = value
+    }
+    return description, nil
+}
+
+// CreateTopic creates a topic.
+func (a *Admin) CreateTopic(name string, opts CreateTopicOptions) error {
+    detail := &sarama.TopicDetail{
+        NumPartitions:     opts.Partitions,
+        ReplicationFactor: opts.ReplicationFactor,
+        ConfigEntries:     configEntries(opts.Configs),
+    }
+    if err := a.admin.CreateTopic(name, detail, opts.ValidateOnly); err != nil {
+        return fmt.Errorf("failed to create topic %s: %w", name, err)
+    }
+    return nil
+}
+
+// DeleteTopic deletes a topic.
+func (a *Admin) DeleteTopic(name string) error {
+    if err := a.admin.DeleteTopic(name); err != nil {
+        return fmt.Errorf("failed to delete topic %s: %w", name, err)
+    }
+    return nil
+}
+
+// AlterTopicConfig sets the given configs of a topic, leaving the others
+// unchanged. A nil value restores the default.
+func (a *Admin) AlterTopicConfig(name string, configs map[string]*string) error {
+    entries := make(map[string]sarama.IncrementalAlterConfigsEntry, len(configs))
+    for key, value := range configs {
+        op := sarama.IncrementalAlterConfigsOperationSet
+        if value == nil {
+            op = sarama.IncrementalAlterConfigsOperationDelete
+        }
+        entries[key] = sarama.IncrementalAlterConfigsEntry{Operation: op, Value: value}
+    }
+    if err := a.admin.IncrementalAlterConfig(sarama.TopicResource, name, entries, false); err != nil {
+        return fmt.Errorf("failed to alter configs of topic %s: %w", name, err)
+    }
+    return nil
+}
+
+// AddPartitions grows a topic to total partitions.
+func (a *Admin) AddPartitions(name string, total int32) error {
+    if err := a.admin.CreatePartitions(name, total, nil, false); err != nil {
+        return fmt.Errorf("failed to add partitions to topic %s: %w", name, err)
+    }
+    return nil
+}
+
+func configEntries(configs map[string]string) map[string]*string {
+    if len(configs) == 0 {
+        return nil
+    }
+    entries := make(map[string]*string, len(configs))
+    for key, value := range configs {
+        entries[key] = &value
+    }
+    return entries
+}
@@ -1 +1,145 @@
-package cli
\ No newline at end of file
+// Package cli implements the ran-kafka command line tool.
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/admin"
+	"github.com/radheem/ran-kafka-client-go/pkg/config"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// globalOptions holds the flags shared by every command and the effective
+// configuration they are merged into.
+type globalOptions struct {
+    configFile string
+    profile    string
+    config     config.Config
+}
+
+// NewRootCommand builds the ran-kafka command tree.
+func NewRootCommand() *cobra.Command {
+    opts := &globalOptions{}
+
+    root := &cobra.Command{
+        Use:           "ran-kafka",
+        Short:         "Produce, consume and administer Kafka",
+        SilenceUsage:  true,
+        SilenceErrors: true,
+        // Flags set on the command line override the configuration file
+        // and the environment. Logs go to stderr so they never mix with
+        // consumed messages.
+        PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+            loaded, err := config.Load(opts.configFile, opts.profile)
+            if err != nil {
+                return err
+            }
+            overlayFlags(cmd.Flags(), &opts.config, *loaded)
+            if err := opts.config.Validate(); err != nil {
+                return err
+            }
+
+            logger, err := logging.New(cmd.ErrOrStderr(), opts.config.Log.Format, opts.config.Log.Level)
+            if err != nil {
+                return err
+            }
+            slog.SetDefault(logger)
+            return nil
+        },
+    }
+    defaults := config.Default()
+    flags := root.PersistentFlags()
+    flags.StringVar(&opts.configFile, "config", "", "configuration file, YAML or TOML (default $"+config.EnvConfig+" or ./"+config.DefaultFile+")")
+    flags.StringVar(&opts.profile, "profile", "", "cluster profile of the configuration file (default $"+config.EnvProfile+")")
+    flags.StringSliceVarP(&opts.config.Cluster.Brokers, "brokers", "b", defaults.Cluster.Brokers, "Kafka broker addresses")
+    flags.StringVar(&opts.config.Log.Format, "log-format", defaults.Log.Format, "log format: text or json")
+    flags.StringVar(&opts.config.Log.Level, "log-level", defaults.Log.Level, "log level: debug, info, warn or error")
+    addSecurityFlags(root, opts)
+
+    root.AddCommand(
+        newProduceCommand(opts),
+        newConsumeCommand(opts),
+        newTopicsCommand(opts),
+        newGroupsCommand(opts),
+        newClusterCommand(opts),
+        newConfigCommand(opts),
+    )
+    return root
+}
+
+// Execute runs the root command and exits non-zero on failure.
+func Execute() {
+    if err := NewRootCommand().Execute(); err != nil {
+        fmt.Fprintln(os.Stderr, "Error:", err)
+        os.Exit(1)
+    }
+}
+
+// addSecurityFlags registers the TLS and SASL flags. Unset flags fall back
+// to the profile and the RAN_KAFKA_TLS_* and RAN_KAFKA_SASL_* environment
+// variables.
+func addSecurityFlags(root *cobra.Command, opts *globalOptions) {
+    tls := &opts.config.Cluster.TLS
+    sasl := &opts.config.Cluster.SASL
+    flags := root.PersistentFlags()
+    flags.BoolVar(&tls.Enabled, "tls", false, "connect to the brokers over TLS")
+    flags.StringVar(&tls.CAFile, "tls-ca-file", "", "PEM file of CAs to trust")
+    flags.StringVar(&tls.CertFile, "tls-cert-file", "", "PEM client certificate for mutual TLS")
+    flags.StringVar(&tls.KeyFile, "tls-key-file", "", "PEM client key for mutual TLS")
+    flags.BoolVar(&tls.InsecureSkipVerify, "tls-insecure-skip-verify", false, "do not verify broker certificates")
+    flags.StringVar(&tls.ServerName, "tls-server-name", "", "server name to verify in broker certificates")
+    flags.StringVar(&sasl.Mechanism, "sasl-mechanism", "", "PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER")
+    flags.StringVar(&sasl.Username, "sasl-username", "", "SASL username")
+    flags.StringVar(&sasl.Password, "sasl-password", "", "SASL password (default $"+kafkaconfig.EnvSASLPassword+")")
+    flags.StringVar(&sasl.OAuthToken, "sasl-oauth-token", "", "static OAUTHBEARER token (default $"+kafkaconfig.EnvSASLOAuthToken+")")
+}
+
+func (o *globalOptions) newClient() (sarama.Client, error) {
+    options, err := o.config.Cluster.Options()
+    if err != nil {
+        return nil, err
+    }
+    saramaConfig := sarama.NewConfig()
+    if err := o.config.Cluster.Security().Apply(saramaConfig); err != nil {
+        return nil, err
+    }
+    overrides := kafkaconfig.ApplyOptions(saramaConfig, options)
+    if err := kafkaconfig.ValidateOverrides(saramaConfig, overrides); err != nil {
+        return nil, fmt.Errorf("invalid client configuration: %w", err)
+    }
+    brokers := o.config.Cluster.Brokers
+    client, err := sarama.NewClient(brokers, saramaConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to %v: %w", brokers, err)
+    }
+    return client, nil
+}
+
+// withAdmin runs fn with an admin client that is closed afterwards.
+func (o *globalOptions) withAdmin(fn func(*admin.Admin) error) error {
+    adminConfig, err := o.config.AdminConfig()
+    if err != nil {
+        return err
+    }
+    a, err := admin.NewAdmin(adminConfig)
+    if err != nil {
+        return err
+    }
+    defer a.Close()
+    return fn(a)
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+)
+
+func newClusterCommand(opts *globalOptions) *cobra.Command {
+    return &cobra.Command{
+        Use:   "cluster",
+        Short: "Show the brokers of the cluster",
+        Args:  cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            client, err := opts.newClient()
+            if err != nil {
+                return err
+            }
+            defer client.Close()
+
+            controller, err := client.Controller()
+            if err != nil {
+                return fmt.Errorf("failed to find controller: %w", err)
+            }
+
+            brokers := client.Brokers()
+            sort.Slice(brokers, func(i, j int) bool { return brokers[i].ID() < brokers[j].ID() })
+
+            w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+            fmt.Fprintln(w, "ID\tADDRESS\tRACK\tCONTROLLER")
+            for _, broker := range brokers {
+                fmt.Fprintf(w, "%d\t%s\t%s\t%t\n", broker.ID(), broker.Addr(), rack(broker), broker.ID() == controller.ID())
+            }
+            return w.Flush()
+        },
+    }
+}
+
+func rack(broker *sarama.Broker) string {
+    if rack := broker.Rack(); rack != "" {
+        return rack
+    }
+    return "-"
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newConfigCommand(opts *globalOptions) *cobra.Command {
+    cmd := &cobra.Command{
+        Use:   "config",
+        Short: "Inspect the configuration",
+    }
+    cmd.AddCommand(&cobra.Command{
+        Use:   "show",
+        Short: "Print the effective configuration with secrets redacted",
+        Long: `Print the configuration after merging the defaults, the configuration
+file, the RAN_KAFKA_* environment variables and the flags, in that order of
+precedence. Passwords, tokens and MongoDB URI credentials are redacted.`,
+        Args: cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            out, err := opts.config.Redacted().YAML()
+            if err != nil {
+                return err
+            }
+            _, err = cmd.OutOrStdout().Write(out)
+            return err
+        },
+    })
+    return cmd
+}
+
+// overlayFlags sets *target to base and then copies back the fields bound to
+// the flags given on the command line, so they take precedence over base.
+// Values are copied as parsed rather than re-parsed from their string form,
+// which is lossy for slice and map flags. Flags bound outside *target keep
+// their value.
+func overlayFlags[T any](flags *pflag.FlagSet, target *T, base T) {
+    parsed := reflect.ValueOf(*target)
+    fields := map[uintptr][]int{}
+    leafFields(reflect.ValueOf(target).Elem(), nil, fields)
+
+    var changed [][]int
+    flags.Visit(func(f *pflag.Flag) {
+        if index, ok := fields[flagAddr(f)]; ok {
+            changed = append(changed, index)
+        }
+    })
+
+    *target = base
+    merged := reflect.ValueOf(target).Elem()
+    for _, index := range changed {
+        merged.FieldByIndex(index).Set(parsed.FieldByIndex(index))
+    }
+}
+
+// leafFields records the index of every non-struct field of v, nested
+// structs included, by its address.
+func leafFields(v reflect.Value, prefix []int, fields map[uintptr][]int) {
+    for i := range v.NumField() {
+        if !v.Type().Field(i).IsExported() {
+            continue
+        }
+        field := v.Field(i)
+        index := append(append([]int(nil), prefix...), i)
+        if field.Kind() == reflect.Struct {
+            leafFields(field, index, fields)
+            continue
+        }
+        fields[field.Addr().Pointer()] = index
+    }
+}
+
+// flagAddr returns the address of the variable a flag is bound to. pflag's
+// values are either a pointer to the variable itself or, for slices and
+// maps, a struct holding that pointer.
+func flagAddr(f *pflag.Flag) uintptr {
+    v := reflect.ValueOf(f.Value)
+    if v.Kind() != reflect.Pointer {
+        return 0
+    }
+    if elem := v.Elem(); elem.Kind() == reflect.Struct {
+        for i := range elem.NumField() {
+            if field := elem.Field(i); field.Kind() == reflect.Pointer {
+                return field.Pointer()
+            }
+        }
+        return 0
+    }
+    return v.Pointer()
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type overlayTarget struct {
+    Name    string
+    Brokers []string
+    Labels  map[string]string
+    Wait    time.Duration
+    Nested  struct {
+        Enabled bool
+        Topics  []string
+    }
+}
+
+func TestOverlayFlags(t *testing.T) {
+    var target overlayTarget
+    var unrelated string
+    flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+    flags.StringVar(&target.Name, "name", "default", "")
+    flags.StringSliceVar(&target.Brokers, "brokers", []string{"localhost:9092"}, "")
+    flags.StringToStringVar(&target.Labels, "label", nil, "")
+    flags.DurationVar(&target.Wait, "wait", time.Second, "")
+    flags.BoolVar(&target.Nested.Enabled, "enabled", false, "")
+    flags.StringArrayVar(&target.Nested.Topics, "topic", nil, "")
+    flags.StringVar(&unrelated, "unrelated", "", "")
+
+    err := flags.Parse([]string{
+        "--brokers", "a:1,b:2", "--brokers", "c:3",
+        "--label", "team=core,env=prod",
+        "--topic", "x,y", "--topic", "[z]",
+        "--enabled",
+        "--unrelated", "kept",
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    base := overlayTarget{
+        Name:    "from-file",
+        Brokers: []string{"file:9092"},
+        Labels:  map[string]string{"owner": "file"},
+        Wait:    time.Minute,
+    }
+    base.Nested.Topics = []string{"file-topic"}
+    overlayFlags(flags, &target, base)
+
+    want := overlayTarget{
+        Name:    "from-file",
+        Brokers: []string{"a:1", "b:2", "c:3"},
+        Labels:  map[string]string{"team": "core", "env": "prod"},
+        Wait:    time.Minute,
+    }
+    want.Nested.Enabled = true
+    want.Nested.Topics = []string{"x,y", "[z]"}
+    if !reflect.DeepEqual(target, want) {
+        t.Errorf("overlay = %+v, want %+v", target, want)
+    }
+    if unrelated != "kept" {
+        t.Errorf("unrelated flag = %q, want %q", unrelated, "kept")
+    }
+}
@@ -0,0 +1,434 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/consumer"
+	"github.com/radheem/ran-kafka-client-go/pkg/metrics"
+	"github.com/spf13/cobra"
+)
+
+type consumeOptions struct {
+    format        string
+    fromBeginning bool
+    fromTime      string
+    offset        string
+    partitions    []int32
+    maxMessages   int
+    timeout       time.Duration
+    noGroup       bool
+    metricsAddr   string
+}
+
+func newConsumeCommand(opts *globalOptions) *cobra.Command {
+    var (
+        config consumer.Config
+        co     consumeOptions
+    )
+
+    cmd := &cobra.Command{
+        Use:   "consume",
+        Short: "Print messages from topics to stdout",
+        Long: `Print messages from topics to stdout.
+
+Formats:
+  raw   the message value
+  json  one JSON object per message with topic, partition, offset, key,
+        value, headers and timestamp
+  any string containing %, kcat style: %t topic, %p partition, %o offset,
+        %k key, %s value, %h headers, %T timestamp (ms), \n newline, \t tab
+
+Flags that are not given fall back to the consumer section of the
+configuration file and the RAN_KAFKA_* environment variables.
+
+Messages are consumed with a consumer group and their offsets committed,
+unless --no-group is set. --partition and --offset imply --no-group.
+--offset is "beginning", "end", an absolute offset or -N for the last N
+messages of each partition.`,
+        Args: cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            base, err := opts.config.ConsumerConfig()
+            if err != nil {
+                return err
+            }
+            overlayFlags(cmd.Flags(), &config, base)
+            if len(config.Topics) == 0 {
+                return errors.New("at least one topic is required, set --topic or consumer.topics")
+            }
+            if co.fromBeginning {
+                config.StartPosition = consumer.StartOldest
+            }
+            if co.fromTime != "" {
+                t, err := time.Parse(time.RFC3339, co.fromTime)
+                if err != nil {
+                    return fmt.Errorf("invalid --from-time: %w", err)
+                }
+                config.StartPosition = consumer.StartAtTime
+                config.StartTime = t
+            }
+            if co.noGroup || co.offset != "" || len(co.partitions) > 0 {
+                return runStandaloneConsume(cmd, opts, config, co)
+            }
+            return runGroupConsume(cmd, config, co)
+        },
+    }
+
+    flags := cmd.Flags()
+    flags.StringSliceVarP(&config.Topics, "topic", "t", nil, "topics to consume")
+    flags.StringVarP(&config.ConsumerGroup, "group", "g", "ran-kafka-consume", "consumer group ID")
+    flags.StringVar(&co.format, "format", outputRaw, "output format: raw, json or a format string")
+    flags.BoolVar(&co.fromBeginning, "from-beginning", false, "start at the oldest message when there is no committed offset")
+    flags.StringVar(&co.fromTime, "from-time", "", "start at an RFC 3339 time when there is no committed offset (group mode only)")
+    flags.StringVar(&co.offset, "offset", "", "start offset: beginning, end, N or -N")
+    flags.Int32SliceVarP(&co.partitions, "partition", "p", nil, "partitions to consume (default all)")
+    flags.IntVarP(&co.maxMessages, "max-messages", "n", 0, "exit after this many messages")
+    flags.DurationVar(&co.timeout, "timeout", 0, "exit when no message arrives for this long")
+    flags.BoolVar(&co.noGroup, "no-group", false, "consume without a consumer group, committing no offsets")
+    flags.StringVar(&config.MongoURI, "mongo-uri", "", "also store messages in this MongoDB (group mode only)")
+    flags.StringVar(&config.MongoDB, "mongo-db", "kafka-messages", "MongoDB database")
+    flags.StringVar(&config.MongoCollection, "mongo-collection", "consumed_messages", "MongoDB collection")
+    flags.BoolVar(&config.MongoIdempotent, "mongo-idempotent", false, "upsert documents under a deterministic _id")
+    flags.IntVar(&config.BatchSize, "batch-size", 0, "batch MongoDB writes up to this many messages")
+    flags.IntVar(&config.Concurrency, "concurrency", 0, "process each partition with this many workers, keeping per-key order")
+    flags.StringVar(&config.DeadLetterTopic, "dead-letter-topic", "", "topic receiving messages that fail processing")
+    flags.IntVar(&config.RetryPolicy.MaxAttempts, "max-attempts", 1, "processing attempts per message")
+    flags.StringVar(&config.RebalanceStrategy, "rebalance-strategy", consumer.RebalanceRoundRobin, "range, roundrobin or sticky")
+    flags.DurationVar(&config.SessionTimeout, "session-timeout", consumer.DefaultSessionTimeout, "group session timeout")
+    flags.DurationVar(&config.HeartbeatInterval, "heartbeat-interval", consumer.DefaultHeartbeatInterval, "group heartbeat interval")
+    flags.DurationVar(&config.RebalanceTimeout, "rebalance-timeout", 0, "time members get to rejoin during a rebalance (default 60s)")
+    flags.DurationVar(&config.MaxProcessingTime, "max-processing-time", 0, "expected per-message processing time (default 100ms)")
+    flags.StringVar(&config.InstanceID, "instance-id", "", "static group membership ID")
+    flags.BoolVar(&config.LogPayloads, "log-payloads", false, "include message values in logs")
+    flags.StringVar(&co.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address under /metrics, e.g. :9090 (group mode only)")
+    return cmd
+}
+
+// messageLimiter enforces --max-messages and --timeout and reports when the
+// consume command should stop.
+type messageLimiter struct {
+    mu       sync.Mutex
+    max      int
+    count    int
+    timeout  time.Duration
+    timer    *time.Timer
+    done     chan struct{}
+    doneOnce sync.Once
+}
+
+func newMessageLimiter(max int, timeout time.Duration) *messageLimiter {
+    l := &messageLimiter{max: max, timeout: timeout, done: make(chan struct{})}
+    if timeout > 0 {
+        l.timer = time.AfterFunc(timeout, l.stop)
+    }
+    return l
+}
+
+// take reserves the next message and reports false once the limit has been
+// reached.
+func (l *messageLimiter) take() bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if l.max > 0 && l.count >= l.max {
+        return false
+    }
+    l.count++
+    if l.timer != nil {
+        l.timer.Reset(l.timeout)
+    }
+    if l.max > 0 && l.count == l.max {
+        l.stop()
+    }
+    return true
+}
+
+func (l *messageLimiter) stop() {
+    l.doneOnce.Do(func() { close(l.done) })
+}
+
+// printHandler prints each message before optionally storing it.
+type printHandler struct {
+    printer *messagePrinter
+    limiter *messageLimiter
+    mu      sync.Mutex
+    store   *consumer.MongoHandler
+}
+
+// Handle implements consumer.Handler
+func (h *printHandler) Handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+    if !h.limiter.take() {
+        return h.wait(ctx)
+    }
+    h.mu.Lock()
+    err := h.printer.print(msg)
+    h.mu.Unlock()
+    if err != nil {
+        return err
+    }
+    if h.store != nil {
+        return h.store.Handle(ctx, msg)
+    }
+    return nil
+}
+
+// wait holds back messages past the limit until the consumer stops, so they
+// are neither printed nor marked.
+func (h *printHandler) wait(ctx context.Context) error {
+    <-ctx.Done()
+    return ctx.Err()
+}
+
+// errLimitReached fails the messages of a batch past --max-messages.
+var errLimitReached = errors.New("message limit reached")
+
+// HandleBatch implements consumer.BatchHandler. Only the messages within the
+// limit are printed and stored; the rest are reported as failed, so the
+// consumer marks the handled prefix and holds the others back.
+func (h *printHandler) HandleBatch(ctx context.Context, msgs []*sarama.ConsumerMessage) error {
+    n := 0
+    for n < len(msgs) && h.limiter.take() {
+        n++
+    }
+    if n == 0 {
+        return h.wait(ctx)
+    }
+
+    h.mu.Lock()
+    for _, msg := range msgs[:n] {
+        if err := h.printer.print(msg); err != nil {
+            h.mu.Unlock()
+            return err
+        }
+    }
+    h.mu.Unlock()
+    if h.store != nil {
+        if err := h.store.HandleBatch(ctx, msgs[:n]); err != nil {
+            return err
+        }
+    }
+
+    if n < len(msgs) {
+        failed := make([]int, 0, len(msgs)-n)
+        for i := n; i < len(msgs); i++ {
+            failed = append(failed, i)
+        }
+        return &consumer.BatchError{Failed: failed, Err: errLimitReached}
+    }
+    return nil
+}
+
+func runGroupConsume(cmd *cobra.Command, config consumer.Config, co consumeOptions) error {
+    printer, err := newMessagePrinter(cmd.OutOrStdout(), co.format)
+    if err != nil {
+        return err
+    }
+    limiter := newMessageLimiter(co.maxMessages, co.timeout)
+    handler := &printHandler{printer: printer, limiter: limiter}
+
+    if co.metricsAddr != "" {
+        ctx, cancel := context.WithCancel(cmd.Context())
+        defer cancel()
+        config.Metrics = metrics.New("")
+        go func() {
+            if err := config.Metrics.Serve(ctx, co.metricsAddr); err != nil {
+                slog.Error("Metrics endpoint failed", slog.String("addr", co.metricsAddr), slog.Any("error", err))
+            }
+        }()
+    }
+
+    // Storage is opt-in and wrapped by the printing handler.
+    if config.MongoURI != "" {
+        store, err := consumer.NewMongoHandler(cmd.Context(), consumer.MongoConfig{
+            URI:            config.MongoURI,
+            Database:       config.MongoDB,
+            Collection:     config.MongoCollection,
+            WriteConcern:   config.MongoWriteConcern,
+            Idempotent:     config.MongoIdempotent,
+            IDKey:          config.MongoIDKey,
+            Logger:         config.Logger,
+            Metrics:        config.Metrics,
+            TracerProvider: config.TracerProvider,
+        })
+        if err != nil {
+            return fmt.Errorf("failed to setup MongoDB: %w", err)
+        }
+        defer store.Close(context.Background())
+        handler.store = store
+    }
+    config.Handler = handler
+
+    c, err := consumer.NewConsumer(config)
+    if err != nil {
+        return fmt.Errorf("failed to create consumer: %w", err)
+    }
+
+    // The consumer stops on SIGINT or SIGTERM, or once the limiter is done,
+    // and commits what it has marked.
+    ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+    defer cancel()
+    go func() {
+        select {
+        case <-limiter.done:
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
+
+    if err := c.Run(ctx); err != nil {
+        return fmt.Errorf("consumer failed: %w", err)
+    }
+    return nil
+}
+
+// runStandaloneConsume reads partitions directly, without joining a group or
+// committing offsets.
+func runStandaloneConsume(cmd *cobra.Command, opts *globalOptions, config consumer.Config, co consumeOptions) error {
+    if config.MongoURI != "" {
+        return errors.New("--mongo-uri requires a consumer group")
+    }
+    if co.fromTime != "" {
+        return errors.New("--from-time requires a consumer group")
+    }
+    printer, err := newMessagePrinter(cmd.OutOrStdout(), co.format)
+    if err != nil {
+        return err
+    }
+
+    client, err := opts.newClient()
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+
+    c, err := sarama.NewConsumerFromClient(client)
+    if err != nil {
+        return fmt.Errorf("failed to create consumer: %w", err)
+    }
+    defer c.Close()
+
+    messages := make(chan *sarama.ConsumerMessage)
+    limiter := newMessageLimiter(co.maxMessages, co.timeout)
+    var wg sync.WaitGroup
+    defer wg.Wait()
+    defer limiter.stop()
+
+    for _, topic := range config.Topics {
+        partitions := co.partitions
+        if len(partitions) == 0 {
+            if partitions, err = client.Partitions(topic); err != nil {
+                return fmt.Errorf("failed to list partitions of %s: %w", topic, err)
+            }
+        }
+        for _, partition := range partitions {
+            offset, err := startOffset(client, topic, partition, co)
+            if err != nil {
+                return err
+            }
+            pc, err := c.ConsumePartition(topic, partition, offset)
+            if err != nil {
+                return fmt.Errorf("failed to consume %s[%d]: %w", topic, partition, err)
+            }
+
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                defer pc.AsyncClose()
+                attrs := []any{slog.String("topic", topic), slog.Int("partition", int(partition))}
+                var lastErr error
+                for {
+                    select {
+                    case msg, ok := <-pc.Messages():
+                        if !ok {
+                            slog.Error("Partition consumer stopped", append(attrs, slog.Any("error", lastErr))...)
+                            return
+                        }
+                        select {
+                        case messages <- msg:
+                        case <-limiter.done:
+                            return
+                        }
+                    case err, ok := <-pc.Errors():
+                        if !ok {
+                            slog.Error("Partition consumer stopped", append(attrs, slog.Any("error", lastErr))...)
+                            return
+                        }
+                        lastErr = err
+                        slog.Error("Failed to consume partition", append(attrs, slog.Any("error", err))...)
+                    case <-limiter.done:
+                        return
+                    }
+                }
+            }()
+        }
+    }
+
+    // stopped closes once every partition consumer has stopped, so the
+    // command does not wait for messages that can no longer arrive.
+    stopped := make(chan struct{})
+    go func() {
+        wg.Wait()
+        close(stopped)
+    }()
+
+    for {
+        select {
+        case <-stopped:
+            select {
+            case <-limiter.done:
+                return nil
+            default:
+                return errors.New("all partition consumers stopped")
+            }
+        case msg := <-messages:
+            if !limiter.take() {
+                return nil
+            }
+            if err := printer.print(msg); err != nil {
+                return err
+            }
+        case <-limiter.done:
+            return nil
+        case <-cmd.Context().Done():
+            return nil
+        }
+    }
+}
+
+// startOffset resolves --offset and --from-beginning for a partition.
+func startOffset(client sarama.Client, topic string, partition int32, co consumeOptions) (int64, error) {
+    switch {
+    case co.offset == "beginning" || (co.offset == "" && co.fromBeginning):
+        return sarama.OffsetOldest, nil
+    case co.offset == "end" || co.offset == "":
+        return sarama.OffsetNewest, nil
+    }
+
+    offset, err := strconv.ParseInt(co.offset, 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid offset %q", co.offset)
+    }
+    if !strings.HasPrefix(co.offset, "-") {
+        return offset, nil
+    }
+
+    // -N starts N messages before the end, but not before the beginning.
+    newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get offset of %s[%d]: %w", topic, partition, err)
+    }
+    oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get offset of %s[%d]: %w", topic, partition, err)
+    }
+    return max(newest+offset, oldest), nil
+}
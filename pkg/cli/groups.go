@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/radheem/ran-kafka-client-go/pkg/admin"
+	"github.com/spf13/cobra"
+)
+
+func newGroupsCommand(opts *globalOptions) *cobra.Command {
+    var output string
+
+    cmd := &cobra.Command{
+        Use:   "groups",
+        Short: "Inspect and manage consumer groups",
+    }
+    cmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "output format: table or json")
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "list",
+        Short: "List consumer groups",
+        Args:  cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                groups, err := a.ListGroups()
+                if err != nil {
+                    return err
+                }
+                return render(cmd.OutOrStdout(), output, groups, func(w io.Writer) {
+                    fmt.Fprintln(w, "NAME\tPROTOCOL TYPE")
+                    for _, g := range groups {
+                        fmt.Fprintf(w, "%s\t%s\n", g.Name, g.ProtocolType)
+                    }
+                })
+            })
+        },
+    })
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "describe GROUP",
+        Short: "Show members, assignments, offsets and lag of a group",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                group, err := a.DescribeGroup(args[0])
+                if err != nil {
+                    return err
+                }
+                return render(cmd.OutOrStdout(), output, group, func(w io.Writer) {
+                    fmt.Fprintf(w, "Group: %s\tState: %s\tProtocol: %s\tTotal lag: %d\n", group.Name, group.State, group.Protocol, group.TotalLag)
+
+                    fmt.Fprintln(w, "\nMEMBER\tINSTANCE\tCLIENT\tHOST\tASSIGNMENT")
+                    for _, m := range group.Members {
+                        fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.MemberID, dash(m.InstanceID), m.ClientID, m.Host, assignment(m.Assignments))
+                    }
+
+                    fmt.Fprintln(w, "\nTOPIC\tPARTITION\tCOMMITTED\tLOG-END\tLAG\tMEMBER")
+                    for _, o := range group.Offsets {
+                        fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\n", o.Topic, o.Partition, offset(o.CommittedOffset), o.LogEndOffset, o.Lag, dash(o.MemberID))
+                    }
+                })
+            })
+        },
+    })
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "delete GROUP",
+        Short: "Delete a consumer group without active members",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                if err := a.DeleteGroup(args[0]); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.OutOrStdout(), "Deleted consumer group %s\n", args[0])
+                return nil
+            })
+        },
+    })
+
+    cmd.AddCommand(newResetOffsetsCommand(opts, &output))
+    return cmd
+}
+
+func newResetOffsetsCommand(opts *globalOptions, output *string) *cobra.Command {
+    var (
+        topics     []string
+        toEarliest bool
+        toLatest   bool
+        toDatetime string
+        shiftBy    int64
+        toOffset   int64
+        dryRun     bool
+        execute    bool
+    )
+
+    cmd := &cobra.Command{
+        Use:   "reset-offsets GROUP",
+        Short: "Move the committed offsets of a group",
+        Long: `Move the committed offsets of a group on the given topics, or on every
+topic it has committed on. Exactly one of --to-earliest, --to-latest,
+--to-datetime, --shift-by and --to-offset is required, as is one of
+--dry-run and --execute. The group must have no active members.`,
+        Args: cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            if dryRun == execute {
+                return errors.New("exactly one of --dry-run and --execute is required")
+            }
+
+            var spec admin.ResetSpec
+            flags := cmd.Flags()
+            chosen := 0
+            if toEarliest {
+                spec.Strategy = admin.ResetToEarliest
+                chosen++
+            }
+            if toLatest {
+                spec.Strategy = admin.ResetToLatest
+                chosen++
+            }
+            if flags.Changed("to-datetime") {
+                t, err := time.Parse(time.RFC3339, toDatetime)
+                if err != nil {
+                    return fmt.Errorf("invalid --to-datetime: %w", err)
+                }
+                spec.Strategy = admin.ResetToDatetime
+                spec.Time = t
+                chosen++
+            }
+            if flags.Changed("shift-by") {
+                spec.Strategy = admin.ResetShiftBy
+                spec.Shift = shiftBy
+                chosen++
+            }
+            if flags.Changed("to-offset") {
+                spec.Strategy = admin.ResetToOffset
+                spec.Offset = toOffset
+                chosen++
+            }
+            if chosen != 1 {
+                return errors.New("exactly one reset strategy is required")
+            }
+
+            return opts.withAdmin(func(a *admin.Admin) error {
+                plan, err := a.PlanOffsetReset(args[0], topics, spec)
+                if err != nil {
+                    return err
+                }
+                if execute {
+                    if err := a.ApplyOffsetReset(args[0], plan); err != nil {
+                        return err
+                    }
+                }
+                return render(cmd.OutOrStdout(), *output, plan, func(w io.Writer) {
+                    fmt.Fprintln(w, "TOPIC\tPARTITION\tCURRENT\tNEW")
+                    for _, r := range plan {
+                        fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", r.Topic, r.Partition, offset(r.Current), r.Target)
+                    }
+                    if dryRun {
+                        fmt.Fprintln(w, "\nDry run, no offsets were changed.")
+                    }
+                })
+            })
+        },
+    }
+
+    flags := cmd.Flags()
+    flags.StringSliceVarP(&topics, "topic", "t", nil, "topics to reset (default every committed topic)")
+    flags.BoolVar(&toEarliest, "to-earliest", false, "reset to the earliest offset")
+    flags.BoolVar(&toLatest, "to-latest", false, "reset to the latest offset")
+    flags.StringVar(&toDatetime, "to-datetime", "", "reset to the first offset at or after an RFC 3339 time")
+    flags.Int64Var(&shiftBy, "shift-by", 0, "move the committed offset by N, negative to rewind; uncommitted partitions move from the earliest offset")
+    flags.Int64Var(&toOffset, "to-offset", 0, "reset to an absolute offset")
+    flags.BoolVar(&dryRun, "dry-run", false, "only show the new offsets")
+    flags.BoolVar(&execute, "execute", false, "commit the new offsets")
+    return cmd
+}
+
+func assignment(topics map[string][]int32) string {
+    parts := make([]string, 0, len(topics))
+    for topic, partitions := range topics {
+        parts = append(parts, topic+ids(partitions))
+    }
+    sort.Strings(parts)
+    return dash(strings.Join(parts, " "))
+}
+
+func offset(o int64) string {
+    if o < 0 {
+        return "-"
+    }
+    return fmt.Sprint(o)
+}
+
+func dash(s string) string {
+    if s == "" {
+        return "-"
+    }
+    return s
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/consumer"
+)
+
+// Output formats accepted by the consume command. Any other value containing
+// a % is treated as a kcat-style format string.
+const (
+    outputRaw  = "raw"
+    outputJSON = "json"
+)
+
+// messagePrinter writes consumed messages to an output stream.
+type messagePrinter struct {
+    out    io.Writer
+    format string
+}
+
+func newMessagePrinter(out io.Writer, format string) (*messagePrinter, error) {
+    if format != outputRaw && format != outputJSON && !strings.Contains(format, "%") {
+        return nil, fmt.Errorf("unknown output format %q", format)
+    }
+    return &messagePrinter{out: out, format: unescapeFormat(format)}, nil
+}
+
+func (p *messagePrinter) print(msg *sarama.ConsumerMessage) error {
+    switch p.format {
+    case outputRaw:
+        _, err := fmt.Fprintf(p.out, "%s\n", msg.Value)
+        return err
+    case outputJSON:
+        b, err := json.Marshal(consumer.NewMessage(msg))
+        if err != nil {
+            return err
+        }
+        _, err = fmt.Fprintf(p.out, "%s\n", b)
+        return err
+    default:
+        _, err := io.WriteString(p.out, formatMessage(p.format, msg))
+        return err
+    }
+}
+
+// formatMessage expands a kcat-style format string:
+//
+//	%t topic, %p partition, %o offset, %k key, %s value,
+//	%h headers as name=value pairs, %T timestamp in milliseconds, %% a %
+func formatMessage(format string, msg *sarama.ConsumerMessage) string {
+    var b strings.Builder
+    for i := 0; i < len(format); i++ {
+        if format[i] != '%' || i == len(format)-1 {
+            b.WriteByte(format[i])
+            continue
+        }
+        i++
+        switch format[i] {
+        case 't':
+            b.WriteString(msg.Topic)
+        case 'p':
+            b.WriteString(strconv.FormatInt(int64(msg.Partition), 10))
+        case 'o':
+            b.WriteString(strconv.FormatInt(msg.Offset, 10))
+        case 'k':
+            b.Write(msg.Key)
+        case 's':
+            b.Write(msg.Value)
+        case 'h':
+            b.WriteString(formatHeaders(msg.Headers))
+        case 'T':
+            b.WriteString(strconv.FormatInt(msg.Timestamp.UnixNano()/int64(time.Millisecond), 10))
+        case '%':
+            b.WriteByte('%')
+        default:
+            b.WriteByte('%')
+            b.WriteByte(format[i])
+        }
+    }
+    return b.String()
+}
+
+func formatHeaders(headers []*sarama.RecordHeader) string {
+    pairs := make([]string, 0, len(headers))
+    for _, h := range headers {
+        pairs = append(pairs, string(h.Key)+"="+string(h.Value))
+    }
+    sort.Strings(pairs)
+    return strings.Join(pairs, ",")
+}
+
+// unescapeFormat turns the \n and \t escapes of a format string typed on the
+// command line into the characters they stand for.
+func unescapeFormat(format string) string {
+    return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`).Replace(format)
+}
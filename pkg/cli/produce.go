@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+	"github.com/spf13/cobra"
+)
+
+// Input formats accepted by the produce command.
+const (
+    inputRaw      = "raw"
+    inputJSON     = "json"
+    inputKeyValue = "kv"
+)
+
+type produceOptions struct {
+    topic     string
+    files     []string
+    format    string
+    separator string
+    key       string
+    headers   []string
+    partition int32
+    report    string
+    maxLine   int
+}
+
+func newProduceCommand(opts *globalOptions) *cobra.Command {
+    var (
+        config producer.Config
+        po     produceOptions
+    )
+
+    cmd := &cobra.Command{
+        Use:   "produce",
+        Short: "Produce line-delimited messages from stdin or files",
+        Long: `Produce one message per input line, read from stdin or the given files.
+
+Formats:
+  raw   the line is the value
+  json  the line is a JSON object with "key", "value" and "headers" fields,
+        as printed by "consume --format json"
+  kv    the line is key<separator>value
+
+The --key expression may contain {n} for the 1-based message number and
+{.field} for a top-level field of a JSON value. It is used for messages
+that have no key of their own.`,
+        Args: cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            base, err := opts.config.ProducerConfig()
+            if err != nil {
+                return err
+            }
+            overlayFlags(cmd.Flags(), &config, base)
+            config.ManualPartitioning = po.partition >= 0
+            return runProduce(cmd, config, po)
+        },
+    }
+
+    flags := cmd.Flags()
+    flags.StringVarP(&po.topic, "topic", "t", "", "topic to produce to")
+    flags.StringSliceVarP(&po.files, "file", "f", nil, "input files, - for stdin (default stdin)")
+    flags.StringVar(&po.format, "format", inputRaw, "input format: raw, json or kv")
+    flags.StringVar(&po.separator, "separator", ":", "key/value separator for the kv format")
+    flags.StringVarP(&po.key, "key", "k", "", "key expression for messages without a key")
+    flags.StringArrayVarP(&po.headers, "header", "H", nil, "header to add to every message, as name=value")
+    flags.Int32VarP(&po.partition, "partition", "p", -1, "partition to produce to, -1 to partition by key")
+    flags.BoolVar(&config.Async, "async", false, "use the async producer")
+    flags.StringVar(&po.report, "report", "summary", "delivery report: message, summary or none")
+    flags.IntVar(&po.maxLine, "max-line-bytes", 1024*1024, "longest accepted input line")
+    flags.StringVar(&config.TransactionalID, "transactional-id", "", "make the producer transactional")
+    flags.BoolVar(&config.LogPayloads, "log-payloads", false, "include message values in debug logs")
+    cmd.MarkFlagRequired("topic")
+    return cmd
+}
+
+func runProduce(cmd *cobra.Command, config producer.Config, po produceOptions) error {
+    switch po.format {
+    case inputRaw, inputJSON, inputKeyValue:
+    default:
+        return fmt.Errorf("unknown input format %q", po.format)
+    }
+    switch po.report {
+    case "message", "summary", "none":
+    default:
+        return fmt.Errorf("unknown report %q", po.report)
+    }
+    if po.maxLine <= 0 {
+        return fmt.Errorf("--max-line-bytes must be positive, got %d", po.maxLine)
+    }
+    headers, err := parseHeaders(po.headers)
+    if err != nil {
+        return err
+    }
+
+    prod, err := producer.NewProducer(config)
+    if err != nil {
+        return err
+    }
+    defer prod.Close()
+
+    // abort ends an open transaction without committing what was sent.
+    abort := func() {}
+    if config.TransactionalID != "" {
+        if err := prod.BeginTxn(); err != nil {
+            return err
+        }
+        abort = func() {
+            if err := prod.AbortTxn(); err != nil {
+                slog.Error("Failed to abort transaction", slog.Any("error", err))
+            }
+        }
+    }
+
+    report := newProduceReport(cmd.OutOrStdout(), po.report)
+    files := po.files
+    if len(files) == 0 {
+        files = []string{"-"}
+    }
+    n := 0
+    for _, name := range files {
+        err := readLines(cmd.InOrStdin(), name, po.maxLine, func(line string) error {
+            n++
+            record, err := parseRecord(line, n, po, headers)
+            if err != nil {
+                return fmt.Errorf("%s: message %d: %w", name, n, err)
+            }
+            if config.Async {
+                return prod.SendRecordAsync(po.topic, record, report.add)
+            }
+            delivery, err := prod.SendRecord(po.topic, record)
+            if err != nil {
+                delivery = &producer.Delivery{Topic: po.topic, Key: record.Key, Err: err}
+            }
+            report.add(delivery)
+            return nil
+        })
+        if err != nil {
+            abort()
+            return err
+        }
+    }
+
+    if err := prod.Flush(cmd.Context()); err != nil {
+        abort()
+        return err
+    }
+    if config.TransactionalID != "" {
+        // A transaction is all or nothing, so a failed send aborts it.
+        if failed := report.failures(); failed > 0 {
+            abort()
+            report.finish()
+            return fmt.Errorf("transaction aborted, %d messages failed", failed)
+        }
+        if err := prod.CommitTxn(); err != nil {
+            abort()
+            return err
+        }
+    }
+    return report.finish()
+}
+
+// readLines calls fn for every line of the named file, or of stdin for "-".
+func readLines(stdin io.Reader, name string, maxLine int, fn func(string) error) error {
+    r := stdin
+    if name != "-" {
+        f, err := os.Open(name)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        r = f
+    }
+
+    scanner := bufio.NewScanner(r)
+    // The scanner's limit is the larger of maxLine and the buffer capacity.
+    scanner.Buffer(make([]byte, 0, min(64*1024, maxLine)), maxLine)
+    for scanner.Scan() {
+        line := strings.TrimSuffix(scanner.Text(), "\r")
+        if line == "" {
+            continue
+        }
+        if err := fn(line); err != nil {
+            return err
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("failed to read %s: %w", name, err)
+    }
+    return nil
+}
+
+func parseHeaders(values []string) (map[string]string, error) {
+    headers := make(map[string]string, len(values))
+    for _, value := range values {
+        name, v, ok := strings.Cut(value, "=")
+        if !ok || name == "" {
+            return nil, fmt.Errorf("invalid header %q, expected name=value", value)
+        }
+        headers[name] = v
+    }
+    return headers, nil
+}
+
+// parseRecord turns an input line into a record according to the format.
+func parseRecord(line string, n int, po produceOptions, headers map[string]string) (producer.Record, error) {
+    record := producer.Record{Partition: po.partition, Headers: map[string]string{}}
+    for k, v := range headers {
+        record.Headers[k] = v
+    }
+
+    switch po.format {
+    case inputRaw:
+        record.Value = []byte(line)
+
+    case inputKeyValue:
+        key, value, ok := strings.Cut(line, po.separator)
+        if !ok {
+            return record, fmt.Errorf("missing separator %q", po.separator)
+        }
+        record.Key = key
+        record.Value = []byte(value)
+
+    case inputJSON:
+        var msg producer.Message
+        if err := json.Unmarshal([]byte(line), &msg); err != nil {
+            return record, fmt.Errorf("invalid JSON: %w", err)
+        }
+        // String values are sent verbatim, matching how the consumer
+        // keeps non-JSON payloads.
+        if s, ok := msg.Value.(string); ok {
+            record.Value = []byte(s)
+        } else {
+            value, err := json.Marshal(msg.Value)
+            if err != nil {
+                return record, err
+            }
+            record.Value = value
+        }
+        record.Key = msg.Key
+        for k, v := range msg.Headers {
+            if _, ok := record.Headers[k]; !ok {
+                record.Headers[k] = v
+            }
+        }
+    }
+
+    if record.Key == "" && po.key != "" {
+        record.Key = expandKey(po.key, n, record.Value)
+    }
+    return record, nil
+}
+
+var keyFieldPattern = regexp.MustCompile(`\{(n|\.[^}]+)\}`)
+
+// expandKey replaces {n} with the message number and {.field} with a
+// top-level field of the JSON value.
+func expandKey(expr string, n int, value []byte) string {
+    var fields map[string]any
+    json.Unmarshal(value, &fields)
+
+    return keyFieldPattern.ReplaceAllStringFunc(expr, func(m string) string {
+        name := m[1 : len(m)-1]
+        if name == "n" {
+            return strconv.Itoa(n)
+        }
+        field, ok := fields[name[1:]]
+        if !ok {
+            return ""
+        }
+        if s, ok := field.(string); ok {
+            return s
+        }
+        b, _ := json.Marshal(field)
+        return string(b)
+    })
+}
+
+// produceReport prints delivery reports as they arrive and a summary at the
+// end. It is safe for use from the async delivery goroutine.
+type produceReport struct {
+    mu         sync.Mutex
+    out        io.Writer
+    mode       string
+    sent       int
+    failed     int
+    partitions map[int32]int
+}
+
+func newProduceReport(out io.Writer, mode string) *produceReport {
+    return &produceReport{out: out, mode: mode, partitions: map[int32]int{}}
+}
+
+func (r *produceReport) add(d *producer.Delivery) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if d.Err != nil {
+        r.failed++
+        fmt.Fprintf(r.out, "failed %s key=%q: %v\n", d.Topic, d.Key, d.Err)
+        return
+    }
+    r.sent++
+    r.partitions[d.Partition]++
+    if r.mode == "message" {
+        fmt.Fprintf(r.out, "%s[%d]@%d key=%q\n", d.Topic, d.Partition, d.Offset, d.Key)
+    }
+}
+
+// failures returns the number of failed messages so far.
+func (r *produceReport) failures() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.failed
+}
+
+func (r *produceReport) finish() error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.mode != "none" {
+        fmt.Fprintf(r.out, "%d messages sent, %d failed\n", r.sent, r.failed)
+        if r.mode == "summary" {
+            partitions := make([]int32, 0, len(r.partitions))
+            for partition := range r.partitions {
+                partitions = append(partitions, partition)
+            }
+            sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+            for _, partition := range partitions {
+                fmt.Fprintf(r.out, "  partition %d: %d\n", partition, r.partitions[partition])
+            }
+        }
+    }
+    if r.failed > 0 {
+        return fmt.Errorf("%d messages failed", r.failed)
+    }
+    return nil
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/radheem/ran-kafka-client-go/pkg/admin"
+	"github.com/spf13/cobra"
+)
+
+func newTopicsCommand(opts *globalOptions) *cobra.Command {
+    var output string
+
+    cmd := &cobra.Command{
+        Use:   "topics",
+        Short: "Manage topics",
+    }
+    cmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "output format: table or json")
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "list",
+        Short: "List topics",
+        Args:  cobra.NoArgs,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                topics, err := a.ListTopics()
+                if err != nil {
+                    return err
+                }
+                return render(cmd.OutOrStdout(), output, topics, func(w io.Writer) {
+                    fmt.Fprintln(w, "NAME\tPARTITIONS\tREPLICATION")
+                    for _, t := range topics {
+                        fmt.Fprintf(w, "%s\t%d\t%d\n", t.Name, t.Partitions, t.ReplicationFactor)
+                    }
+                })
+            })
+        },
+    })
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "describe TOPIC",
+        Short: "Show partitions, replicas and configs of a topic",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                topic, err := a.DescribeTopic(args[0])
+                if err != nil {
+                    return err
+                }
+                return render(cmd.OutOrStdout(), output, topic, func(w io.Writer) {
+                    fmt.Fprintf(w, "Topic: %s\tInternal: %t\n", topic.Name, topic.Internal)
+                    for _, key := range sortedKeys(topic.Configs) {
+                        fmt.Fprintf(w, "Config: %s=%s\n", key, topic.Configs[key])
+                    }
+                    fmt.Fprintln(w, "\nPARTITION\tLEADER\tREPLICAS\tISR\tOFFLINE")
+                    for _, p := range topic.Partitions {
+                        fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", p.ID, p.Leader, ids(p.Replicas), ids(p.ISR), ids(p.OfflineReplicas))
+                    }
+                })
+            })
+        },
+    })
+
+    cmd.AddCommand(newTopicsCreateCommand(opts))
+
+    cmd.AddCommand(&cobra.Command{
+        Use:   "delete TOPIC",
+        Short: "Delete a topic",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                if err := a.DeleteTopic(args[0]); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.OutOrStdout(), "Deleted topic %s\n", args[0])
+                return nil
+            })
+        },
+    })
+
+    var (
+        set   []string
+        reset []string
+    )
+    alter := &cobra.Command{
+        Use:   "alter-config TOPIC",
+        Short: "Set or reset configs of a topic",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            configs := map[string]*string{}
+            values, err := parseConfigs(set)
+            if err != nil {
+                return err
+            }
+            for key, value := range values {
+                configs[key] = &value
+            }
+            for _, key := range reset {
+                configs[key] = nil
+            }
+            if len(configs) == 0 {
+                return fmt.Errorf("nothing to change, use --set or --reset")
+            }
+            return opts.withAdmin(func(a *admin.Admin) error {
+                if err := a.AlterTopicConfig(args[0], configs); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.OutOrStdout(), "Altered configs of topic %s\n", args[0])
+                return nil
+            })
+        },
+    }
+    alter.Flags().StringArrayVar(&set, "set", nil, "config to set, as name=value")
+    alter.Flags().StringArrayVar(&reset, "reset", nil, "config to restore to its default")
+    cmd.AddCommand(alter)
+
+    var total int32
+    addPartitions := &cobra.Command{
+        Use:   "add-partitions TOPIC",
+        Short: "Increase the partition count of a topic",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            return opts.withAdmin(func(a *admin.Admin) error {
+                if err := a.AddPartitions(args[0], total); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.OutOrStdout(), "Topic %s now has %d partitions\n", args[0], total)
+                return nil
+            })
+        },
+    }
+    addPartitions.Flags().Int32Var(&total, "partitions", 0, "new total number of partitions")
+    addPartitions.MarkFlagRequired("partitions")
+    cmd.AddCommand(addPartitions)
+
+    return cmd
+}
+
+func newTopicsCreateCommand(opts *globalOptions) *cobra.Command {
+    var (
+        options admin.CreateTopicOptions
+        configs []string
+    )
+
+    cmd := &cobra.Command{
+        Use:   "create TOPIC",
+        Short: "Create a topic",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            var err error
+            if options.Configs, err = parseConfigs(configs); err != nil {
+                return err
+            }
+            return opts.withAdmin(func(a *admin.Admin) error {
+                if err := a.CreateTopic(args[0], options); err != nil {
+                    return err
+                }
+                fmt.Fprintf(cmd.OutOrStdout(), "Created topic %s\n", args[0])
+                return nil
+            })
+        },
+    }
+
+    flags := cmd.Flags()
+    flags.Int32VarP(&options.Partitions, "partitions", "p", 1, "number of partitions")
+    flags.Int16VarP(&options.ReplicationFactor, "replication-factor", "r", -1, "replication factor, -1 for the broker default")
+    flags.StringArrayVarP(&configs, "config", "c", nil, "topic config, as name=value")
+    flags.BoolVar(&options.ValidateOnly, "validate-only", false, "only validate the request")
+    return cmd
+}
+
+func parseConfigs(values []string) (map[string]string, error) {
+    configs := make(map[string]string, len(values))
+    for _, value := range values {
+        name, v, ok := strings.Cut(value, "=")
+        if !ok || name == "" {
+            return nil, fmt.Errorf("invalid config %q, expected name=value", value)
+        }
+        configs[name] = v
+    }
+    return configs, nil
+}
+
+// render writes v as indented JSON or lets table write a tab-separated table.
+func render(out io.Writer, format string, v any, table func(io.Writer)) error {
+    switch format {
+    case "json":
+        enc := json.NewEncoder(out)
+        enc.SetIndent("", "  ")
+        return enc.Encode(v)
+    case "table":
+        w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+        table(w)
+        return w.Flush()
+    default:
+        return fmt.Errorf("unknown output format %q", format)
+    }
+}
+
+func ids(values []int32) string {
+    parts := make([]string, len(values))
+    for i, v := range values {
+        parts[i] = fmt.Sprint(v)
+    }
+    return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,471 @@
+// Package config loads the client configuration from a YAML or TOML file
+// with named cluster profiles, the RAN_KAFKA_* environment variables and
+// command line flags.
+//
+// Settings are layered with increasing precedence: built-in defaults, the
+// configuration file, the environment and finally flags, which callers
+// apply to the loaded Config before calling Validate.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/admin"
+	"github.com/radheem/ran-kafka-client-go/pkg/consumer"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is loaded from the working directory when no file is given.
+const DefaultFile = "ran-kafka.yaml"
+
+// DefaultProfile is used when neither the caller nor the file names one.
+const DefaultProfile = "default"
+
+// Redacted replaces secrets in the output of Config.Redacted.
+const Redacted = "REDACTED"
+
+// File is the layout of the configuration file.
+type File struct {
+    // Profile names the profile used when none is selected explicitly.
+    Profile  string             `yaml:"profile" toml:"profile"`
+    Profiles map[string]Cluster `yaml:"profiles" toml:"profiles"`
+    Log      Log                `yaml:"log" toml:"log"`
+    Producer Producer           `yaml:"producer" toml:"producer"`
+    Consumer Consumer           `yaml:"consumer" toml:"consumer"`
+}
+
+// Config is the effective configuration: the selected profile's cluster
+// together with the client settings.
+type Config struct {
+    Profile  string   `yaml:"profile" toml:"profile"`
+    Cluster  Cluster  `yaml:"cluster" toml:"cluster"`
+    Log      Log      `yaml:"log" toml:"log"`
+    Producer Producer `yaml:"producer" toml:"producer"`
+    Consumer Consumer `yaml:"consumer" toml:"consumer"`
+}
+
+// Cluster is a named cluster profile.
+type Cluster struct {
+    Brokers []string `yaml:"brokers" toml:"brokers"`
+    // ClientID and Version, e.g. "3.6.0", are passed to sarama when set.
+    ClientID string `yaml:"client_id" toml:"client_id"`
+    Version  string `yaml:"version" toml:"version"`
+    TLS      TLS    `yaml:"tls" toml:"tls"`
+    SASL     SASL   `yaml:"sasl" toml:"sasl"`
+}
+
+// TLS mirrors kafkaconfig.TLS.
+type TLS struct {
+    Enabled            bool   `yaml:"enabled" toml:"enabled"`
+    CAFile             string `yaml:"ca_file" toml:"ca_file"`
+    CertFile           string `yaml:"cert_file" toml:"cert_file"`
+    KeyFile            string `yaml:"key_file" toml:"key_file"`
+    InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+    ServerName         string `yaml:"server_name" toml:"server_name"`
+}
+
+// SASL mirrors kafkaconfig.SASL. OAuthToken is used as a static
+// OAUTHBEARER token.
+type SASL struct {
+    Mechanism  string `yaml:"mechanism" toml:"mechanism"`
+    Username   string `yaml:"username" toml:"username"`
+    Password   string `yaml:"password" toml:"password"`
+    OAuthToken string `yaml:"oauth_token" toml:"oauth_token"`
+}
+
+// Log holds the logging settings shared by every client.
+type Log struct {
+    Format string `yaml:"format" toml:"format"`
+    Level  string `yaml:"level" toml:"level"`
+    // Payloads enables payload logging, truncated to MaxPayload bytes.
+    Payloads   bool `yaml:"payloads" toml:"payloads"`
+    MaxPayload int  `yaml:"max_payload" toml:"max_payload"`
+}
+
+// Producer holds the producer settings. Zero sarama settings keep the
+// producer defaults.
+type Producer struct {
+    Async           bool   `yaml:"async" toml:"async"`
+    TransactionalID string `yaml:"transactional_id" toml:"transactional_id"`
+    // Acks is "all", "leader" or "none".
+    Acks string `yaml:"acks" toml:"acks"`
+    // Compression is "none", "gzip", "snappy", "lz4" or "zstd".
+    Compression     string        `yaml:"compression" toml:"compression"`
+    MaxMessageBytes int           `yaml:"max_message_bytes" toml:"max_message_bytes"`
+    Linger          time.Duration `yaml:"linger" toml:"linger"`
+    Retries         int           `yaml:"retries" toml:"retries"`
+}
+
+// Consumer holds the consumer group settings.
+type Consumer struct {
+    Group  string   `yaml:"group" toml:"group"`
+    Topics []string `yaml:"topics" toml:"topics"`
+    // StartPosition is "newest", "oldest" or an RFC 3339 time.
+    StartPosition      string        `yaml:"start_position" toml:"start_position"`
+    RebalanceStrategy  string        `yaml:"rebalance_strategy" toml:"rebalance_strategy"`
+    SessionTimeout     time.Duration `yaml:"session_timeout" toml:"session_timeout"`
+    HeartbeatInterval  time.Duration `yaml:"heartbeat_interval" toml:"heartbeat_interval"`
+    RebalanceTimeout   time.Duration `yaml:"rebalance_timeout" toml:"rebalance_timeout"`
+    MaxProcessingTime  time.Duration `yaml:"max_processing_time" toml:"max_processing_time"`
+    InstanceID         string        `yaml:"instance_id" toml:"instance_id"`
+    BatchSize          int           `yaml:"batch_size" toml:"batch_size"`
+    BatchFlushInterval time.Duration `yaml:"batch_flush_interval" toml:"batch_flush_interval"`
+    Concurrency        int           `yaml:"concurrency" toml:"concurrency"`
+    DeadLetterTopic    string        `yaml:"dead_letter_topic" toml:"dead_letter_topic"`
+    MaxAttempts        int           `yaml:"max_attempts" toml:"max_attempts"`
+    // FetchMin, FetchDefault, FetchMax and MaxWait tune fetch requests;
+    // zero keeps sarama's defaults.
+    FetchMin     int32         `yaml:"fetch_min" toml:"fetch_min"`
+    FetchDefault int32         `yaml:"fetch_default" toml:"fetch_default"`
+    FetchMax     int32         `yaml:"fetch_max" toml:"fetch_max"`
+    MaxWait      time.Duration `yaml:"max_wait" toml:"max_wait"`
+    Mongo        Mongo         `yaml:"mongo" toml:"mongo"`
+}
+
+// Mongo holds the optional MongoDB sink settings.
+type Mongo struct {
+    URI          string `yaml:"uri" toml:"uri"`
+    Database     string `yaml:"database" toml:"database"`
+    Collection   string `yaml:"collection" toml:"collection"`
+    WriteConcern string `yaml:"write_concern" toml:"write_concern"`
+    Idempotent   bool   `yaml:"idempotent" toml:"idempotent"`
+}
+
+// Default returns the built-in defaults.
+func Default() Config {
+    return Config{
+        Cluster: Cluster{Brokers: []string{"localhost:9092"}},
+        Log:     Log{Format: "text", Level: "info"},
+        Consumer: Consumer{
+            Group:             "ran-kafka-consume",
+            StartPosition:     "newest",
+            RebalanceStrategy: consumer.RebalanceRoundRobin,
+            SessionTimeout:    consumer.DefaultSessionTimeout,
+            HeartbeatInterval: consumer.DefaultHeartbeatInterval,
+            MaxAttempts:       1,
+            Mongo: Mongo{
+                Database:   "kafka-messages",
+                Collection: "consumed_messages",
+            },
+        },
+    }
+}
+
+// Load builds the configuration from the defaults, the file at path and
+// the environment. An empty path falls back to $RAN_KAFKA_CONFIG and then
+// to DefaultFile, which may be missing. An empty profile falls back to
+// $RAN_KAFKA_PROFILE, the file's profile and then DefaultProfile.
+//
+// The result is not validated so that flags can still be applied to it;
+// call Validate once every layer has been applied.
+func Load(path, profile string) (*Config, error) {
+    config := Default()
+
+    explicit := path != ""
+    if path == "" {
+        path = os.Getenv(EnvConfig)
+        explicit = path != ""
+    }
+    if path == "" {
+        path = DefaultFile
+    }
+    if profile == "" {
+        profile = os.Getenv(EnvProfile)
+    }
+
+    file := File{Log: config.Log, Producer: config.Producer, Consumer: config.Consumer}
+    if err := readFile(path, &file); err != nil {
+        if explicit || !errors.Is(err, os.ErrNotExist) {
+            return nil, err
+        }
+    }
+    config.Log = file.Log
+    config.Producer = file.Producer
+    config.Consumer = file.Consumer
+
+    if profile == "" {
+        profile = file.Profile
+    }
+    if _, ok := file.Profiles[DefaultProfile]; ok && profile == "" {
+        profile = DefaultProfile
+    }
+    if profile != "" {
+        cluster, ok := file.Profiles[profile]
+        if !ok {
+            return nil, fmt.Errorf("unknown profile %q, available: %s", profile, strings.Join(profileNames(file.Profiles), ", "))
+        }
+        if len(cluster.Brokers) == 0 {
+            cluster.Brokers = config.Cluster.Brokers
+        }
+        config.Profile = profile
+        config.Cluster = cluster
+    }
+
+    if err := config.applyEnv(); err != nil {
+        return nil, err
+    }
+    return &config, nil
+}
+
+// readFile decodes a YAML or TOML file, chosen by its extension, rejecting
+// unknown keys.
+func readFile(path string, file *File) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("failed to read config file: %w", err)
+    }
+
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".toml":
+        meta, err := toml.Decode(string(data), file)
+        if err != nil {
+            return fmt.Errorf("failed to parse %s: %w", path, err)
+        }
+        if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+            return fmt.Errorf("failed to parse %s: unknown key %s", path, undecoded[0])
+        }
+    default:
+        decoder := yaml.NewDecoder(bytes.NewReader(data))
+        decoder.KnownFields(true)
+        if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+            return fmt.Errorf("failed to parse %s: %w", path, err)
+        }
+    }
+    return nil
+}
+
+func profileNames(profiles map[string]Cluster) []string {
+    names := make([]string, 0, len(profiles))
+    for name := range profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Validate checks the configuration without connecting to the cluster.
+func (c *Config) Validate() error {
+    if len(c.Cluster.Brokers) == 0 {
+        return errors.New("at least one broker is required")
+    }
+    if _, err := logging.New(io.Discard, c.Log.Format, c.Log.Level); err != nil {
+        return err
+    }
+    if c.Consumer.Mongo.URI != "" && (c.Consumer.Mongo.Database == "" || c.Consumer.Mongo.Collection == "") {
+        return errors.New("consumer.mongo needs a database and a collection")
+    }
+    // These also check the cluster's security settings.
+    producerConfig, err := c.ProducerConfig()
+    if err != nil {
+        return err
+    }
+    if err := producerConfig.Validate(); err != nil {
+        return err
+    }
+    consumerConfig, err := c.ConsumerConfig()
+    if err != nil {
+        return err
+    }
+    return consumerConfig.Validate()
+}
+
+// Security converts the cluster's TLS and SASL settings.
+func (c Cluster) Security() kafkaconfig.Security {
+    security := kafkaconfig.Security{
+        TLS: kafkaconfig.TLS(c.TLS),
+        SASL: kafkaconfig.SASL{
+            Mechanism: c.SASL.Mechanism,
+            Username:  c.SASL.Username,
+            Password:  c.SASL.Password,
+        },
+    }
+    if c.SASL.OAuthToken != "" {
+        security.SASL.TokenProvider = kafkaconfig.StaticToken(c.SASL.OAuthToken)
+    }
+    return security
+}
+
+// ProducerConfig returns the producer configuration.
+func (c *Config) ProducerConfig() (producer.Config, error) {
+    options, err := c.Cluster.Options()
+    if err != nil {
+        return producer.Config{}, err
+    }
+    p := c.Producer
+    if p.Acks != "" {
+        acks, err := kafkaconfig.ParseRequiredAcks(p.Acks)
+        if err != nil {
+            return producer.Config{}, fmt.Errorf("invalid producer acks: %w", err)
+        }
+        options = append(options, kafkaconfig.WithRequiredAcks(acks))
+    }
+    if p.Compression != "" {
+        var codec sarama.CompressionCodec
+        if err := codec.UnmarshalText([]byte(p.Compression)); err != nil {
+            return producer.Config{}, fmt.Errorf("invalid producer compression: %w", err)
+        }
+        options = append(options, kafkaconfig.WithCompression(codec))
+    }
+    if p.MaxMessageBytes > 0 {
+        options = append(options, kafkaconfig.WithMaxMessageBytes(p.MaxMessageBytes))
+    }
+    if p.Linger > 0 {
+        options = append(options, kafkaconfig.WithLinger(p.Linger))
+    }
+    if p.Retries > 0 {
+        options = append(options, kafkaconfig.WithRetryMax(p.Retries))
+    }
+
+    return producer.Config{
+        Brokers:         c.Cluster.Brokers,
+        Security:        c.Cluster.Security(),
+        Async:           p.Async,
+        TransactionalID: p.TransactionalID,
+        SaramaOptions:   options,
+        LogPayloads:     c.Log.Payloads,
+        MaxLogPayload:   c.Log.MaxPayload,
+    }, nil
+}
+
+// Options returns the sarama overrides shared by every client.
+func (c Cluster) Options() ([]kafkaconfig.Option, error) {
+    var options []kafkaconfig.Option
+    if c.ClientID != "" {
+        options = append(options, kafkaconfig.WithClientID(c.ClientID))
+    }
+    if c.Version != "" {
+        version, err := sarama.ParseKafkaVersion(c.Version)
+        if err != nil {
+            return nil, fmt.Errorf("invalid Kafka version: %w", err)
+        }
+        options = append(options, kafkaconfig.WithVersion(version))
+    }
+    return options, nil
+}
+
+// AdminConfig returns the admin client configuration.
+func (c *Config) AdminConfig() (admin.Config, error) {
+    options, err := c.Cluster.Options()
+    if err != nil {
+        return admin.Config{}, err
+    }
+    return admin.Config{
+        Brokers:       c.Cluster.Brokers,
+        Security:      c.Cluster.Security(),
+        SaramaOptions: options,
+    }, nil
+}
+
+// ConsumerConfig returns the consumer configuration. Its Handler is left
+// for the caller to set.
+func (c *Config) ConsumerConfig() (consumer.Config, error) {
+    options, err := c.Cluster.Options()
+    if err != nil {
+        return consumer.Config{}, err
+    }
+    if c.Consumer.FetchMin > 0 || c.Consumer.FetchDefault > 0 || c.Consumer.FetchMax > 0 {
+        options = append(options, kafkaconfig.WithFetchSizes(c.Consumer.FetchMin, c.Consumer.FetchDefault, c.Consumer.FetchMax))
+    }
+    if c.Consumer.MaxWait > 0 {
+        options = append(options, kafkaconfig.WithMaxWait(c.Consumer.MaxWait))
+    }
+
+    config := consumer.Config{
+        Brokers:            c.Cluster.Brokers,
+        Security:           c.Cluster.Security(),
+        Topics:             c.Consumer.Topics,
+        ConsumerGroup:      c.Consumer.Group,
+        RebalanceStrategy:  c.Consumer.RebalanceStrategy,
+        SessionTimeout:     c.Consumer.SessionTimeout,
+        HeartbeatInterval:  c.Consumer.HeartbeatInterval,
+        RebalanceTimeout:   c.Consumer.RebalanceTimeout,
+        MaxProcessingTime:  c.Consumer.MaxProcessingTime,
+        InstanceID:         c.Consumer.InstanceID,
+        BatchSize:          c.Consumer.BatchSize,
+        BatchFlushInterval: c.Consumer.BatchFlushInterval,
+        Concurrency:        c.Consumer.Concurrency,
+        DeadLetterTopic:    c.Consumer.DeadLetterTopic,
+        RetryPolicy:        consumer.RetryPolicy{MaxAttempts: c.Consumer.MaxAttempts},
+        MongoURI:           c.Consumer.Mongo.URI,
+        MongoDB:            c.Consumer.Mongo.Database,
+        MongoCollection:    c.Consumer.Mongo.Collection,
+        MongoWriteConcern:  c.Consumer.Mongo.WriteConcern,
+        MongoIdempotent:    c.Consumer.Mongo.Idempotent,
+        SaramaOptions:      options,
+        LogPayloads:        c.Log.Payloads,
+        MaxLogPayload:      c.Log.MaxPayload,
+    }
+
+    switch c.Consumer.StartPosition {
+    case "", "newest":
+        config.StartPosition = consumer.StartNewest
+    case "oldest":
+        config.StartPosition = consumer.StartOldest
+    default:
+        t, err := time.Parse(time.RFC3339, c.Consumer.StartPosition)
+        if err != nil {
+            return consumer.Config{}, fmt.Errorf("invalid consumer start position %q, expected newest, oldest or an RFC 3339 time", c.Consumer.StartPosition)
+        }
+        config.StartPosition = consumer.StartAtTime
+        config.StartTime = t
+    }
+    return config, nil
+}
+
+// Redacted returns a copy of the configuration that is safe to print, with
+// passwords, tokens and MongoDB URI credentials replaced. A MongoDB URI that
+// does not parse, such as some multi-host forms, is replaced entirely.
+func (c Config) Redacted() Config {
+    if c.Cluster.SASL.Password != "" {
+        c.Cluster.SASL.Password = Redacted
+    }
+    if c.Cluster.SASL.OAuthToken != "" {
+        c.Cluster.SASL.OAuthToken = Redacted
+    }
+    if c.Consumer.Mongo.URI != "" {
+        c.Consumer.Mongo.URI = redactURI(c.Consumer.Mongo.URI)
+    }
+    return c
+}
+
+// redactURI replaces the password in uri, or the whole URI when it cannot
+// be parsed.
+func redactURI(uri string) string {
+    u, err := url.Parse(uri)
+    if err != nil {
+        return Redacted
+    }
+    if u.User == nil {
+        return uri
+    }
+    if _, ok := u.User.Password(); !ok {
+        return uri
+    }
+    u.User = url.UserPassword(u.User.Username(), Redacted)
+    return u.String()
+}
+
+// YAML encodes the configuration as YAML.
+func (c Config) YAML() ([]byte, error) {
+    var buf bytes.Buffer
+    encoder := yaml.NewEncoder(&buf)
+    encoder.SetIndent(2)
+    if err := encoder.Encode(c); err != nil {
+        return nil, fmt.Errorf("failed to encode config: %w", err)
+    }
+    return buf.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+)
+
+// Environment variables read by Load, in addition to the kafkaconfig
+// security variables. Lists are comma separated.
+const (
+    EnvConfig          = "RAN_KAFKA_CONFIG"
+    EnvProfile         = "RAN_KAFKA_PROFILE"
+    EnvBrokers         = "RAN_KAFKA_BROKERS"
+    EnvLogFormat       = "RAN_KAFKA_LOG_FORMAT"
+    EnvLogLevel        = "RAN_KAFKA_LOG_LEVEL"
+    EnvGroup           = "RAN_KAFKA_GROUP"
+    EnvTopics          = "RAN_KAFKA_TOPICS"
+    EnvMongoURI        = "RAN_KAFKA_MONGO_URI"
+    EnvMongoDatabase   = "RAN_KAFKA_MONGO_DATABASE"
+    EnvMongoCollection = "RAN_KAFKA_MONGO_COLLECTION"
+)
+
+// applyEnv overrides the settings whose environment variable is set.
+func (c *Config) applyEnv() error {
+    envList(&c.Cluster.Brokers, EnvBrokers)
+    envString(&c.Log.Format, EnvLogFormat)
+    envString(&c.Log.Level, EnvLogLevel)
+
+    if err := envBool(&c.Cluster.TLS.Enabled, kafkaconfig.EnvTLSEnabled); err != nil {
+        return err
+    }
+    envString(&c.Cluster.TLS.CAFile, kafkaconfig.EnvTLSCAFile)
+    envString(&c.Cluster.TLS.CertFile, kafkaconfig.EnvTLSCertFile)
+    envString(&c.Cluster.TLS.KeyFile, kafkaconfig.EnvTLSKeyFile)
+    if err := envBool(&c.Cluster.TLS.InsecureSkipVerify, kafkaconfig.EnvTLSInsecureSkipVerify); err != nil {
+        return err
+    }
+    envString(&c.Cluster.TLS.ServerName, kafkaconfig.EnvTLSServerName)
+    envString(&c.Cluster.SASL.Mechanism, kafkaconfig.EnvSASLMechanism)
+    envString(&c.Cluster.SASL.Username, kafkaconfig.EnvSASLUsername)
+    envString(&c.Cluster.SASL.Password, kafkaconfig.EnvSASLPassword)
+    envString(&c.Cluster.SASL.OAuthToken, kafkaconfig.EnvSASLOAuthToken)
+
+    envString(&c.Consumer.Group, EnvGroup)
+    envList(&c.Consumer.Topics, EnvTopics)
+    envString(&c.Consumer.Mongo.URI, EnvMongoURI)
+    envString(&c.Consumer.Mongo.Database, EnvMongoDatabase)
+    envString(&c.Consumer.Mongo.Collection, EnvMongoCollection)
+    return nil
+}
+
+func envString(target *string, name string) {
+    if value := os.Getenv(name); value != "" {
+        *target = value
+    }
+}
+
+func envList(target *[]string, name string) {
+    value := os.Getenv(name)
+    if value == "" {
+        return
+    }
+    var list []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            list = append(list, item)
+        }
+    }
+    *target = list
+}
+
+func envBool(target *bool, name string) error {
+    value := os.Getenv(name)
+    if value == "" {
+        return nil
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        return fmt.Errorf("invalid %s %q: %w", name, value, err)
+    }
+    *target = b
+    return nil
+}
@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultBatchFlushInterval is used when batching is enabled without a
+// BatchFlushInterval.
+const DefaultBatchFlushInterval = time.Second
+
+// BatchHandler is implemented by handlers that can process several messages
+// of a claim at once. When Config.BatchSize is above 1 the consumer gathers
+// messages and marks them only after HandleBatch returns nil.
+type BatchHandler interface {
+    Handler
+    HandleBatch(ctx context.Context, msgs []*sarama.ConsumerMessage) error
+}
+
+// BatchError is returned by HandleBatch when only some messages of a batch
+// failed, such as the rejected documents of an unordered bulk insert. The
+// consumer then processes only the failed messages one at a time, so the
+// others are not handled twice.
+type BatchError struct {
+    // Failed holds the indexes of the failed messages in the batch.
+    Failed []int
+    Err    error
+}
+
+func (e *BatchError) Error() string {
+    return fmt.Sprintf("%d messages of the batch failed: %v", len(e.Failed), e.Err)
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// failedMessages reports which messages of a batch of size n failed with
+// err. Every message failed unless err is a *BatchError.
+func failedMessages(err error, n int) []bool {
+    failed := make([]bool, n)
+    var batchErr *BatchError
+    if !errors.As(err, &batchErr) {
+        for i := range failed {
+            failed[i] = true
+        }
+        return failed
+    }
+    for _, i := range batchErr.Failed {
+        if i >= 0 && i < n {
+            failed[i] = true
+        }
+    }
+    return failed
+}
+
+// consumeBatches gathers messages of a claim until BatchSize is reached or
+// the flush interval elapses, then hands them to the batch handler.
+func (c *Consumer) consumeBatches(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim, handler BatchHandler) error {
+    interval := c.config.BatchFlushInterval
+    if interval <= 0 {
+        interval = DefaultBatchFlushInterval
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    batch := make([]*sarama.ConsumerMessage, 0, c.config.BatchSize)
+    flush := func(ctx context.Context) error {
+        if len(batch) == 0 {
+            return nil
+        }
+        defer func() { batch = batch[:0] }()
+
+        // The batch span links the traces of its messages.
+        links := make([]trace.Link, 0, len(batch))
+        for _, message := range batch {
+            msgCtx := c.propagator.Extract(ctx, tracing.ConsumerHeaders{Msg: message})
+            if sc := trace.SpanContextFromContext(msgCtx); sc.IsValid() {
+                links = append(links, trace.Link{SpanContext: sc})
+            }
+        }
+        spanCtx, span := c.tracer.Start(ctx, "process "+claim.Topic(),
+            trace.WithSpanKind(trace.SpanKindConsumer),
+            trace.WithLinks(links...),
+            trace.WithAttributes(tracing.MessageAttributes(claim.Topic(), nil)...),
+            trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(batch))))
+
+        start := time.Now()
+        spanCtx, err := c.decode(spanCtx, batch...)
+        if err == nil {
+            err = handler.HandleBatch(spanCtx, batch)
+        }
+        tracing.EndSpan(span, err)
+        c.metrics.ObserveBatch(claim.Topic(), len(batch))
+        c.metrics.ObserveProcess(claim.Topic(), len(batch), time.Since(start), err)
+        if err != nil {
+            if ctx.Err() != nil {
+                // The messages before the first failed one were handled.
+                handled := 0
+                for _, failed := range failedMessages(err, len(batch)) {
+                    if failed {
+                        break
+                    }
+                    handled++
+                }
+                if handled > 0 {
+                    session.MarkMessage(batch[handled-1], "")
+                }
+                return ctx.Err()
+            }
+            // Fall back to one message at a time so retries and the
+            // dead-letter topic apply to the messages that actually fail.
+            // Messages the handler reported as written are only marked.
+            c.logger.Warn("Batch failed, processing messages individually",
+                slog.String("topic", claim.Topic()),
+                slog.Int("partition", int(claim.Partition())),
+                slog.Int("size", len(batch)),
+                slog.Any("error", err))
+            for i, failed := range failedMessages(err, len(batch)) {
+                if !failed {
+                    session.MarkMessage(batch[i], "")
+                    continue
+                }
+                if err := c.consumeMessage(session, batch[i]); err != nil {
+                    return err
+                }
+            }
+            return nil
+        }
+
+        // A claim covers a single partition, so marking the last message
+        // commits the whole batch.
+        session.MarkMessage(batch[len(batch)-1], "")
+        return nil
+    }
+
+    // The in-flight batch is still written when the claim is revoked, so it
+    // is part of the commit that ends the generation.
+    drain := func() error {
+        if err := flush(context.WithoutCancel(session.Context())); err != nil {
+            c.logger.Warn("Failed to flush batch on revoke",
+                slog.String("topic", claim.Topic()),
+                slog.Int("partition", int(claim.Partition())),
+                slog.Any("error", err))
+        }
+        return nil
+    }
+
+    for {
+        select {
+        case message := <-claim.Messages():
+            if message == nil {
+                return drain()
+            }
+            c.metrics.SetLag(message.Topic, message.Partition, message.Offset, claim.HighWaterMarkOffset())
+            if err := c.waitNotBefore(session.Context(), message); err != nil {
+                return nil
+            }
+
+            batch = append(batch, message)
+            if len(batch) >= c.config.BatchSize {
+                if err := flush(session.Context()); err != nil {
+                    return c.stopClaim(session, err)
+                }
+            }
+
+        case <-ticker.C:
+            if err := flush(session.Context()); err != nil {
+                return c.stopClaim(session, err)
+            }
+
+        case <-c.ctx.Done():
+            return drain()
+        }
+    }
+}
@@ -0,0 +1,36 @@
+package consumer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFailedMessages(t *testing.T) {
+    rejected := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+        {WriteError: mongo.WriteError{Index: 1, Code: 11000}},
+        {WriteError: mongo.WriteError{Index: 3, Code: 121}},
+    }}
+    unacknowledged := rejected
+    unacknowledged.WriteConcernError = &mongo.WriteConcernError{Code: 64}
+
+    tests := []struct {
+        name string
+        err  error
+        want []bool
+    }{
+        {"plain error", errors.New("connection reset"), []bool{true, true, true, true}},
+        {"rejected documents", SinkError(bulkWriteError(rejected)), []bool{false, true, false, true}},
+        {"write concern error", SinkError(bulkWriteError(unacknowledged)), []bool{true, true, true, true}},
+        {"index out of range", &BatchError{Failed: []int{0, 7}}, []bool{true, false, false, false}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := failedMessages(tt.err, 4); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("failedMessages = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
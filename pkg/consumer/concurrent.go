@@ -0,0 +1,140 @@
+package consumer
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// workerQueueSize is the number of messages buffered per worker before the
+// claim stops dispatching.
+const workerQueueSize = 64
+
+// offsetTracker records the messages of a claim in dispatch order and
+// reports the highest offset below which every message has completed.
+type offsetTracker struct {
+    mu      sync.Mutex
+    pending []int64
+    done    map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+    return &offsetTracker{done: map[int64]bool{}}
+}
+
+func (t *offsetTracker) dispatched(offset int64) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.pending = append(t.pending, offset)
+}
+
+// completed marks offset as processed. It returns the next offset to commit
+// and true when the contiguous completed range advanced.
+func (t *offsetTracker) completed(offset int64) (int64, bool) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    t.done[offset] = true
+    advanced := false
+    var last int64
+    for len(t.pending) > 0 && t.done[t.pending[0]] {
+        last = t.pending[0]
+        delete(t.done, last)
+        t.pending = t.pending[1:]
+        advanced = true
+    }
+    return last + 1, advanced
+}
+
+// workerFor hashes the key of msg onto one of n workers so messages with the
+// same key are processed in order. Messages without a key are spread by
+// offset.
+func workerFor(msg *sarama.ConsumerMessage, n int) int {
+    if len(msg.Key) == 0 {
+        return int(msg.Offset % int64(n))
+    }
+    h := fnv.New32a()
+    h.Write(msg.Key)
+    return int(h.Sum32() % uint32(n))
+}
+
+// consumeConcurrently fans the messages of a claim out to Concurrency
+// workers. Only the highest contiguous completed offset is marked, so a slow
+// message holds back the commit of the messages after it.
+func (c *Consumer) consumeConcurrently(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+    ctx, cancel := context.WithCancel(session.Context())
+    defer cancel()
+
+    tracker := newOffsetTracker()
+    var (
+        wg       sync.WaitGroup
+        errOnce  sync.Once
+        firstErr error
+    )
+
+    queues := make([]chan *sarama.ConsumerMessage, c.config.Concurrency)
+    for i := range queues {
+        queues[i] = make(chan *sarama.ConsumerMessage, workerQueueSize)
+        wg.Add(1)
+        go func(queue <-chan *sarama.ConsumerMessage) {
+            defer wg.Done()
+            for message := range queue {
+                // After a failure the remaining messages are left unmarked
+                // and redelivered.
+                if ctx.Err() != nil {
+                    continue
+                }
+                // A message left unmarked by handleMessage still completes,
+                // as a later mark would skip it in serial mode too.
+                if _, err := c.handleMessage(session, message); err != nil {
+                    errOnce.Do(func() { firstErr = err })
+                    cancel()
+                    continue
+                }
+                if next, ok := tracker.completed(message.Offset); ok {
+                    session.MarkOffset(message.Topic, message.Partition, next, "")
+                }
+            }
+        }(queues[i])
+    }
+
+    // Workers finish the messages they are handling before the claim
+    // returns, so their offsets are part of the commit that ends the
+    // generation. Queued messages are only handled when the claim ends
+    // normally; after a failure or the end of the session they are skipped
+    // and redelivered.
+    stop := func() error {
+        for _, queue := range queues {
+            close(queue)
+        }
+        wg.Wait()
+        return c.stopClaim(session, firstErr)
+    }
+
+    for {
+        select {
+        case message := <-claim.Messages():
+            if message == nil {
+                return stop()
+            }
+            c.metrics.SetLag(message.Topic, message.Partition, message.Offset, claim.HighWaterMarkOffset())
+            if err := c.waitNotBefore(ctx, message); err != nil {
+                return stop()
+            }
+
+            tracker.dispatched(message.Offset)
+            select {
+            case queues[workerFor(message, len(queues))] <- message:
+            case <-ctx.Done():
+                return stop()
+            }
+
+        case <-ctx.Done():
+            return stop()
+        case <-c.ctx.Done():
+            return stop()
+        }
+    }
+}
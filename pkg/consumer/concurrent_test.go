@@ -0,0 +1,65 @@
+package consumer
+
+import "testing"
+
+func TestOffsetTracker(t *testing.T) {
+    type step struct {
+        offset   int64
+        next     int64
+        advanced bool
+    }
+    tests := []struct {
+        name       string
+        dispatched []int64
+        steps      []step
+    }{
+        {
+            name:       "in order",
+            dispatched: []int64{1, 2, 3},
+            steps:      []step{{1, 2, true}, {2, 3, true}, {3, 4, true}},
+        },
+        {
+            name:       "out of order",
+            dispatched: []int64{1, 2, 3},
+            steps:      []step{{3, 0, false}, {2, 0, false}, {1, 4, true}},
+        },
+        {
+            name:       "partially out of order",
+            dispatched: []int64{1, 2, 3, 4},
+            steps:      []step{{2, 0, false}, {1, 3, true}, {4, 0, false}, {3, 5, true}},
+        },
+        {
+            // Compacted topics and transaction markers leave gaps between
+            // offsets.
+            name:       "gaps",
+            dispatched: []int64{5, 7, 10},
+            steps:      []step{{7, 0, false}, {5, 8, true}, {10, 11, true}},
+        },
+        {
+            // A failed message never completes and holds back the offsets
+            // after it, so they are redelivered with it.
+            name:       "failed offset",
+            dispatched: []int64{1, 2, 3},
+            steps:      []step{{2, 0, false}, {3, 0, false}},
+        },
+        {
+            name:       "failed offset after completed ones",
+            dispatched: []int64{1, 2, 3, 4},
+            steps:      []step{{1, 2, true}, {3, 0, false}, {4, 0, false}},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tracker := newOffsetTracker()
+            for _, offset := range tt.dispatched {
+                tracker.dispatched(offset)
+            }
+            for _, s := range tt.steps {
+                next, advanced := tracker.completed(s.offset)
+                if advanced != s.advanced || (advanced && next != s.next) {
+                    t.Errorf("completed(%d) = %d, %t, want %d, %t", s.offset, next, advanced, s.next, s.advanced)
+                }
+            }
+        })
+    }
+}
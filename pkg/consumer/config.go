@@ -0,0 +1,129 @@
+package consumer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+)
+
+// Rebalance strategies accepted by Config.RebalanceStrategy.
+const (
+    RebalanceRange             = "range"
+    RebalanceRoundRobin        = "roundrobin"
+    RebalanceSticky            = "sticky"
+    RebalanceCooperativeSticky = "cooperative-sticky"
+)
+
+// Group timing defaults applied when the Config leaves them zero.
+const (
+    DefaultSessionTimeout    = 10 * time.Second
+    DefaultHeartbeatInterval = 3 * time.Second
+)
+
+// DefaultSaramaConfig returns the sarama configuration consumers start
+// from, which is sarama's own default.
+func DefaultSaramaConfig() *sarama.Config {
+    return sarama.NewConfig()
+}
+
+// Validate checks config the way NewConsumer does, without connecting.
+func (config Config) Validate() error {
+    _, _, err := newSaramaConfig(config)
+    return err
+}
+
+// newSaramaConfig validates the group settings of config and builds the
+// sarama configuration from them, the base configuration and the sarama
+// overrides. It returns the names of the applied overrides.
+func newSaramaConfig(config Config) (*sarama.Config, []string, error) {
+    saramaConfig := kafkaconfig.BaseConfig(config.Sarama, DefaultSaramaConfig)
+
+    strategy, err := balanceStrategy(config.RebalanceStrategy)
+    if err != nil {
+        return nil, nil, err
+    }
+    saramaConfig.Consumer.Group.Rebalance.Strategy = strategy
+
+    saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+    switch config.StartPosition {
+    case StartNewest:
+    case StartOldest:
+        saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+    case StartAtTime:
+        if config.StartTime.IsZero() {
+            return nil, nil, fmt.Errorf("StartAtTime requires a StartTime")
+        }
+    default:
+        return nil, nil, fmt.Errorf("unknown start position %d", config.StartPosition)
+    }
+
+    if config.Concurrency > 1 && config.BatchSize > 1 {
+        return nil, nil, fmt.Errorf("Concurrency and BatchSize cannot be combined")
+    }
+
+    session := durationOr(config.SessionTimeout, DefaultSessionTimeout)
+    heartbeat := durationOr(config.HeartbeatInterval, DefaultHeartbeatInterval)
+    if config.SessionTimeout < 0 || config.HeartbeatInterval < 0 || config.RebalanceTimeout < 0 || config.MaxProcessingTime < 0 {
+        return nil, nil, fmt.Errorf("group timeouts must not be negative")
+    }
+    if heartbeat*3 > session {
+        return nil, nil, fmt.Errorf("heartbeat interval %v must be at most a third of the session timeout %v", heartbeat, session)
+    }
+    saramaConfig.Consumer.Group.Session.Timeout = session
+    saramaConfig.Consumer.Group.Heartbeat.Interval = heartbeat
+    if config.RebalanceTimeout > 0 {
+        if config.RebalanceTimeout < session {
+            return nil, nil, fmt.Errorf("rebalance timeout %v must not be below the session timeout %v", config.RebalanceTimeout, session)
+        }
+        saramaConfig.Consumer.Group.Rebalance.Timeout = config.RebalanceTimeout
+    }
+    if config.MaxProcessingTime > 0 {
+        saramaConfig.Consumer.MaxProcessingTime = config.MaxProcessingTime
+    }
+
+    // Static membership needs the JoinGroup v5 protocol.
+    if config.InstanceID != "" {
+        saramaConfig.Consumer.Group.InstanceId = config.InstanceID
+        if !saramaConfig.Version.IsAtLeast(sarama.V2_3_0_0) {
+            saramaConfig.Version = sarama.V2_3_0_0
+        }
+    }
+
+    if err := config.Security.Apply(saramaConfig); err != nil {
+        return nil, nil, err
+    }
+
+    overrides := kafkaconfig.ApplyOptions(saramaConfig, config.SaramaOptions)
+    // Group and commit errors are drained and reported through OnError.
+    saramaConfig.Consumer.Return.Errors = true
+
+    if err := kafkaconfig.ValidateOverrides(saramaConfig, overrides); err != nil {
+        return nil, nil, fmt.Errorf("invalid consumer configuration: %w", err)
+    }
+    return saramaConfig, overrides, nil
+}
+
+func balanceStrategy(name string) (sarama.BalanceStrategy, error) {
+    switch name {
+    case "", RebalanceRoundRobin:
+        return sarama.NewBalanceStrategyRoundRobin(), nil
+    case RebalanceRange:
+        return sarama.NewBalanceStrategyRange(), nil
+    case RebalanceSticky:
+        return sarama.NewBalanceStrategySticky(), nil
+    case RebalanceCooperativeSticky:
+        // sarama only implements the eager rebalance protocol.
+        return nil, fmt.Errorf("rebalance strategy %q is not supported by sarama, use %q", name, RebalanceSticky)
+    default:
+        return nil, fmt.Errorf("unknown rebalance strategy %q", name)
+    }
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+    if d == 0 {
+        return def
+    }
+    return d
+}
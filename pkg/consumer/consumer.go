@@ -2,78 +2,268 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"os/signal"
+	"log/slog"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/IBM/sarama"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/metrics"
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+	"github.com/radheem/ran-kafka-client-go/pkg/serde"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
-    Brokers       []string
-    Topics        []string
-    ConsumerGroup string
-    MongoURI      string
-    MongoDB       string
+    Brokers         []string
+    Topics          []string
+    ConsumerGroup   string
+    MongoURI        string
+    MongoDB         string
     MongoCollection string
+
+    // Security configures TLS and SASL, for the retry producer as well.
+    Security kafkaconfig.Security
+
+    // Sarama, if set, replaces DefaultSaramaConfig as the base the consumer
+    // applies its group settings to. It is copied, not modified.
+    Sarama *sarama.Config
+    // SaramaOptions override individual sarama settings, such as fetch
+    // sizes or the Kafka version, after everything else. They are also
+    // applied to the dead-letter and retry producer. The applied overrides
+    // are logged when the consumer is created.
+    SaramaOptions []kafkaconfig.Option
+
+    // StartPosition is where a group without committed offsets starts.
+    // StartTime is used with StartAtTime.
+    StartPosition StartPosition
+    StartTime     time.Time
+
+    // RebalanceStrategy is RebalanceRoundRobin (the default),
+    // RebalanceRange or RebalanceSticky.
+    RebalanceStrategy string
+    // SessionTimeout and HeartbeatInterval default to 10s and 3s. The
+    // heartbeat must be at most a third of the session timeout.
+    SessionTimeout    time.Duration
+    HeartbeatInterval time.Duration
+    // RebalanceTimeout is how long members get to rejoin during a
+    // rebalance. Defaults to 60s and may not be below SessionTimeout.
+    RebalanceTimeout time.Duration
+    // MaxProcessingTime is how long the handler may take per message
+    // before sarama pauses fetching for the partition. Defaults to 100ms.
+    MaxProcessingTime time.Duration
+    // InstanceID enables static membership: a restarted member with the
+    // same ID gets its partitions back without a rebalance, as long as it
+    // returns within SessionTimeout. Requires Kafka 2.3 or later.
+    InstanceID string
+    // ReadyTimeout bounds how long Run waits for the first group
+    // assignment. Zero waits until the context is cancelled.
+    ReadyTimeout time.Duration
+
+    // OnAssigned is called with the claimed partitions at the start of
+    // every group generation, before any message is consumed, e.g. to load
+    // local caches. An error aborts the generation before any claim starts
+    // and is reported as an ErrorGroup; the consumer then rejoins the group
+    // and calls the hook again, unless ErrorPolicy makes ErrorGroup fatal.
+    OnAssigned RebalanceHook
+    // OnRevoked is called at the end of every generation once all claims
+    // have stopped, before offsets are committed, e.g. to flush
+    // per-partition state.
+    OnRevoked RebalanceHook
+
+    // Handler processes each consumed message. When nil, messages are
+    // stored in MongoDB if MongoURI is set and logged otherwise.
+    Handler Handler
+    // ValueDeserializer, if set, decodes every message value, e.g. from
+    // Avro with a schema registry, before the handler is called. Handlers
+    // read the result with DecodedValue, and the Mongo handler stores it in
+    // place of the JSON-parsed value.
+    ValueDeserializer serde.Deserializer
+
+    // DeadLetterTopic, if set, receives messages that failed processing.
+    // They are republished with their origin, the error and the attempt
+    // count as headers and then marked.
+    DeadLetterTopic string
+    // DeadLetterFailurePolicy controls what happens when the retry or
+    // dead-letter publish fails as well. Defaults to DeadLetterStop.
+    DeadLetterFailurePolicy DeadLetterFailurePolicy
+
+    // RetryPolicy is applied in-process before a failed message is given up
+    // on. The zero value does not retry.
+    RetryPolicy RetryPolicy
+    // RetryTopics enables non-blocking retries: messages that still fail
+    // with a retriable error are forwarded to the next tier topic, which the
+    // consumer subscribes to as well, instead of blocking the partition.
+    RetryTopics []RetryTopic
+
+    // BatchSize enables batching when above 1 and the handler implements
+    // BatchHandler, as the built-in Mongo handler does. A batch is flushed
+    // when it is full or every BatchFlushInterval.
+    BatchSize          int
+    BatchFlushInterval time.Duration
+    // MongoWriteConcern is "majority", a number of nodes or a tag set name.
+    // Empty keeps the server default.
+    MongoWriteConcern string
+    // MongoIdempotent upserts documents under a deterministic _id derived
+    // from MongoIDKey (see MongoConfig.IDKey) instead of inserting them.
+    MongoIdempotent bool
+    MongoIDKey      string
+
+    // Concurrency enables key-ordered concurrent processing when above 1:
+    // the messages of each claimed partition are spread over this many
+    // workers by key hash, and only the highest contiguous completed offset
+    // is marked. It cannot be combined with BatchSize.
+    Concurrency int
+
+    // OnError is called with every classified error: failed messages,
+    // store and republish failures, group and commit errors. It must not
+    // block.
+    OnError func(err *Error)
+    // ErrorPolicy decides which error kinds stop the consumer. By default
+    // every kind is retried.
+    ErrorPolicy ErrorPolicy
+
+    // Logger receives the consumer's logs. Defaults to slog.Default().
+    Logger *slog.Logger
+    // LogPayloads adds message values, truncated to MaxLogPayload bytes, to
+    // the per-message logs.
+    LogPayloads   bool
+    MaxLogPayload int
+
+    // Metrics, if set, records consume counts, processing and sink
+    // latencies and per-partition lag, along with sarama's own metrics
+    // under the group name.
+    Metrics *metrics.Metrics
+
+    // TracerProvider creates a consume span around every handler call,
+    // continuing the trace found in the message headers, and a child span
+    // around MongoDB writes. Defaults to the global provider.
+    TracerProvider trace.TracerProvider
+    // Propagator extracts the trace context from message headers. Defaults
+    // to W3C trace context (traceparent and tracestate).
+    Propagator propagation.TextMapPropagator
 }
 
+// ErrReadyTimeout is returned by Run when the group is not joined within
+// Config.ReadyTimeout.
+var ErrReadyTimeout = errors.New("consumer group not joined")
+
 type Consumer struct {
-    config       Config
-    client       sarama.ConsumerGroup
-    mongoClient  *mongo.Client
-    mongoCollection *mongo.Collection
-    ready        chan bool
-    ctx          context.Context
-    cancel       context.CancelFunc
-    wg           sync.WaitGroup
+    config      Config
+    logger      *slog.Logger
+    metrics     *metrics.Metrics
+    unregister  func()
+    tracer      trace.Tracer
+    propagator  propagation.TextMapPropagator
+    kafka       sarama.Client
+    client      sarama.ConsumerGroup
+    handler     Handler
+    mongo       *MongoHandler
+    republisher *producer.Producer
+    ready       chan struct{}
+    ctx         context.Context
+    cancel      context.CancelFunc
+    wg          sync.WaitGroup
+    stopOnce    sync.Once
+    // readyOnce closes ready on the first generation only; Setup runs
+    // again after every rebalance.
+    readyOnce sync.Once
+
+    // fatalErr is the error that stopped the consumer under ErrorFatal.
+    fatalOnce sync.Once
+    fatalErr  error
+
+    // seekMu guards the pending seeks and the cancel func of the current
+    // group session.
+    seekMu        sync.Mutex
+    seeks         map[topicPartition]int64
+    seekTime      time.Time
+    sessionCancel context.CancelFunc
 }
 
 type Message struct {
-    Topic     string                 `json:"topic"`
-    Partition int32                  `json:"partition"`
-    Offset    int64                  `json:"offset"`
-    Key       string                 `json:"key,omitempty"`
-    Value     any                    `json:"value"`
-    Headers   map[string]string      `json:"headers,omitempty"`
-    Timestamp time.Time              `json:"timestamp"`
+    // ID is only set when the Mongo sink stores messages idempotently.
+    ID        string            `json:"-" bson:"_id,omitempty"`
+    Topic     string            `json:"topic"`
+    Partition int32             `json:"partition"`
+    Offset    int64             `json:"offset"`
+    Key       string            `json:"key,omitempty"`
+    Value     any               `json:"value"`
+    Headers   map[string]string `json:"headers,omitempty"`
+    Timestamp time.Time         `json:"timestamp"`
 }
 
 func NewConsumer(config Config) (*Consumer, error) {
-    // Setup Sarama configuration
-    saramaConfig := sarama.NewConfig()
-    saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRoundRobin()
-    saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
-    saramaConfig.Consumer.Group.Session.Timeout = 10 * time.Second
-    saramaConfig.Consumer.Group.Heartbeat.Interval = 3 * time.Second
-
-    // Create consumer group client
-    client, err := sarama.NewConsumerGroup(config.Brokers, config.ConsumerGroup, saramaConfig)
+    saramaConfig, overrides, err := newSaramaConfig(config)
     if err != nil {
+        return nil, err
+    }
+    logger := logging.OrDefault(config.Logger)
+    if len(overrides) > 0 {
+        logger.Info("Applied sarama overrides", slog.Any("overrides", overrides))
+    }
+
+    // Create consumer group client. The underlying client is also used to
+    // look up offsets for seeks.
+    kafka, err := sarama.NewClient(config.Brokers, saramaConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create client: %w", err)
+    }
+    client, err := sarama.NewConsumerGroupFromClient(config.ConsumerGroup, kafka)
+    if err != nil {
+        kafka.Close()
         return nil, fmt.Errorf("failed to create consumer group: %w", err)
     }
 
     ctx, cancel := context.WithCancel(context.Background())
 
     consumer := &Consumer{
-        config: config,
-        client: client,
-        ready:  make(chan bool),
-        ctx:    ctx,
-        cancel: cancel,
+        config:     config,
+        logger:     logger,
+        metrics:    config.Metrics,
+        kafka:      kafka,
+        unregister: config.Metrics.RegisterSarama(config.ConsumerGroup, saramaConfig.MetricRegistry),
+        tracer:     tracing.Tracer(config.TracerProvider),
+        propagator: tracing.Propagator(config.Propagator),
+        client:     client,
+        handler:    config.Handler,
+        ready:      make(chan struct{}),
+        ctx:        ctx,
+        cancel:     cancel,
+        seeks:      map[topicPartition]int64{},
+    }
+
+    // Fall back to the built-in handlers
+    if consumer.handler == nil {
+        if config.MongoURI != "" {
+            if err := consumer.setupMongo(); err != nil {
+                cancel()
+                consumer.unregister()
+                client.Close()
+                kafka.Close()
+                return nil, fmt.Errorf("failed to setup MongoDB: %w", err)
+            }
+            consumer.handler = consumer.mongo
+        } else {
+            consumer.handler = LogHandler(consumer.logger, consumer.payload())
+        }
     }
 
-    // Setup MongoDB if configured
-    if config.MongoURI != "" {
-        if err := consumer.setupMongo(); err != nil {
-            return nil, fmt.Errorf("failed to setup MongoDB: %w", err)
+    if config.DeadLetterTopic != "" || len(config.RetryTopics) > 0 {
+        if err := consumer.setupRepublisher(); err != nil {
+            consumer.Stop()
+            return nil, fmt.Errorf("failed to setup retry producer: %w", err)
+        }
+    }
+    if len(config.RetryTopics) > 0 {
+        if err := consumer.checkRetryTopics(); err != nil {
+            consumer.Stop()
+            return nil, err
         }
     }
 
@@ -81,105 +271,158 @@ func NewConsumer(config Config) (*Consumer, error) {
 }
 
 func (c *Consumer) setupMongo() error {
-    clientOptions := options.Client().ApplyURI(c.config.MongoURI)
-    client, err := mongo.Connect(c.ctx, clientOptions)
+    handler, err := NewMongoHandler(c.ctx, MongoConfig{
+        URI:            c.config.MongoURI,
+        Database:       c.config.MongoDB,
+        Collection:     c.config.MongoCollection,
+        WriteConcern:   c.config.MongoWriteConcern,
+        Idempotent:     c.config.MongoIdempotent,
+        IDKey:          c.config.MongoIDKey,
+        Logger:         c.logger,
+        Metrics:        c.metrics,
+        TracerProvider: c.config.TracerProvider,
+    })
     if err != nil {
         return err
     }
 
-    // Test connection
-    if err := client.Ping(c.ctx, nil); err != nil {
-        return err
-    }
-
-    c.mongoClient = client
-    c.mongoCollection = client.Database(c.config.MongoDB).Collection(c.config.MongoCollection)
-    
-    log.Printf("Connected to MongoDB: %s/%s", c.config.MongoDB, c.config.MongoCollection)
+    c.mongo = handler
     return nil
 }
 
-func (c *Consumer) Start() error {
-    log.Printf("Starting consumer for topics: %v", c.config.Topics)
+// Run consumes until ctx is cancelled, Stop is called or an error is fatal
+// under the ErrorPolicy, and then shuts the consumer down. It returns nil on
+// cancellation and the fatal *Error otherwise. With a ReadyTimeout, Run fails with
+// ErrReadyTimeout if the group has not been joined in time.
+func (c *Consumer) Run(ctx context.Context) error {
+    c.logger.Info("Starting consumer", slog.Any("topics", c.subscribedTopics()), slog.String("group", c.config.ConsumerGroup))
+    defer c.Stop()
+
+    stop := context.AfterFunc(ctx, c.cancel)
+    defer stop()
 
+    // The errors channel is closed when the group is closed by Stop.
+    go c.drainGroupErrors()
+
+    errs := make(chan error, 1)
     c.wg.Add(1)
     go func() {
         defer c.wg.Done()
-        for {
-            select {
-            case <-c.ctx.Done():
-                log.Println("Consumer context cancelled")
-                return
-            default:
-                if err := c.client.Consume(c.ctx, c.config.Topics, c); err != nil {
-                    log.Printf("Error from consumer: %v", err)
-                    return
-                }
-            }
-        }
+        errs <- c.consume()
     }()
 
-    // Wait for consumer to be ready
-    <-c.ready
-    log.Println("Consumer is ready and consuming messages")
-    
-    // Setup signal handling
-    sigterm := make(chan os.Signal, 1)
-    signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
+    var timeout <-chan time.Time
+    if c.config.ReadyTimeout > 0 {
+        timer := time.NewTimer(c.config.ReadyTimeout)
+        defer timer.Stop()
+        timeout = timer.C
+    }
 
     select {
-    case <-sigterm:
-        log.Println("Termination signal received")
-    case <-c.ctx.Done():
-        log.Println("Consumer context done")
+    case <-c.ready:
+        c.logger.Info("Consumer is ready and consuming messages")
+    case err := <-errs:
+        return err
+    case <-timeout:
+        return fmt.Errorf("%w after %v", ErrReadyTimeout, c.config.ReadyTimeout)
     }
+    return <-errs
+}
 
-    c.Stop()
-    return nil
+// Ready returns a channel that is closed once the consumer has joined the
+// group and received its first assignment.
+func (c *Consumer) Ready() <-chan struct{} {
+    return c.ready
+}
+
+// consume runs group sessions until the consumer is stopped, rejoining after
+// failed sessions unless group errors are fatal.
+func (c *Consumer) consume() error {
+    for c.ctx.Err() == nil {
+        err := c.consumeSession()
+        if err == nil || c.ctx.Err() != nil {
+            continue
+        }
+        if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+            return fmt.Errorf("consumer group failed: %w", err)
+        }
+
+        c.logger.Error("Consumer group session failed", slog.Any("error", err))
+        if !c.reportGroupError(err) {
+            sleep(c.ctx, groupRetryBackoff)
+        }
+    }
+    c.logger.Debug("Consumer context cancelled")
+    return c.fatalErr
+}
+
+// consumeSession runs one group session under a context that Seek can cancel.
+func (c *Consumer) consumeSession() error {
+    ctx, cancel := context.WithCancel(c.ctx)
+    defer cancel()
+
+    c.seekMu.Lock()
+    c.sessionCancel = cancel
+    c.seekMu.Unlock()
+
+    return c.client.Consume(ctx, c.subscribedTopics(), c)
 }
 
+// Stop shuts the consumer down and makes Run return. It is safe to call more
+// than once and from another goroutine than Run.
 func (c *Consumer) Stop() {
-    log.Println("Stopping consumer...")
+    c.stopOnce.Do(c.stop)
+}
+
+func (c *Consumer) stop() {
+    c.logger.Info("Stopping consumer")
     c.cancel()
     c.wg.Wait()
-    
+
     if err := c.client.Close(); err != nil {
-        log.Printf("Error closing consumer: %v", err)
+        c.logger.Error("Failed to close consumer group", slog.Any("error", err))
+    }
+    if err := c.kafka.Close(); err != nil {
+        c.logger.Error("Failed to close client", slog.Any("error", err))
     }
-    
-    if c.mongoClient != nil {
-        if err := c.mongoClient.Disconnect(c.ctx); err != nil {
-            log.Printf("Error disconnecting from MongoDB: %v", err)
+    c.unregister()
+
+    if c.republisher != nil {
+        if err := c.republisher.Close(); err != nil {
+            c.logger.Error("Failed to close retry producer", slog.Any("error", err))
         }
     }
-    
-    log.Println("Consumer stopped")
-}
 
-// Setup implements sarama.ConsumerGroupHandler
-func (c *Consumer) Setup(sarama.ConsumerGroupSession) error {
-    close(c.ready)
-    return nil
-}
+    if c.mongo != nil {
+        if err := c.mongo.Close(context.Background()); err != nil {
+            c.logger.Error("Failed to disconnect from MongoDB", slog.Any("error", err))
+        }
+    }
 
-// Cleanup implements sarama.ConsumerGroupHandler
-func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
-    return nil
+    c.logger.Info("Consumer stopped")
 }
 
 // ConsumeClaim implements sarama.ConsumerGroupHandler
 func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+    if c.config.Concurrency > 1 {
+        return c.consumeConcurrently(session, claim)
+    }
+    if handler, ok := c.handler.(BatchHandler); ok && c.config.BatchSize > 1 {
+        return c.consumeBatches(session, claim, handler)
+    }
+
     for {
         select {
         case message := <-claim.Messages():
             if message == nil {
                 return nil
             }
-            
-            if err := c.processMessage(message); err != nil {
-                log.Printf("Error processing message: %v", err)
-            } else {
-                session.MarkMessage(message, "")
+            c.metrics.SetLag(message.Topic, message.Partition, message.Offset, claim.HighWaterMarkOffset())
+            if err := c.waitNotBefore(session.Context(), message); err != nil {
+                return nil
+            }
+            if err := c.consumeMessage(session, message); err != nil {
+                return c.stopClaim(session, err)
             }
 
         case <-c.ctx.Done():
@@ -188,50 +431,76 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
     }
 }
 
-func (c *Consumer) processMessage(msg *sarama.ConsumerMessage) error {
-    // Parse message value as JSON if possible, otherwise store as string
-    var value interface{}
-    if err := json.Unmarshal(msg.Value, &value); err != nil {
-        value = string(msg.Value)
+// consumeMessage processes a single message with handleMessage and marks
+// it. An error means the claim must stop.
+func (c *Consumer) consumeMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) error {
+    mark, err := c.handleMessage(session, message)
+    if err != nil {
+        return err
     }
-
-    // Convert headers
-    headers := make(map[string]string)
-    for _, header := range msg.Headers {
-        headers[string(header.Key)] = string(header.Value)
+    if mark {
+        session.MarkMessage(message, "")
     }
+    return nil
+}
 
-    message := Message{
-        Topic:     msg.Topic,
-        Partition: msg.Partition,
-        Offset:    msg.Offset,
-        Key:       string(msg.Key),
-        Value:     value,
-        Headers:   headers,
-        Timestamp: msg.Timestamp,
+// handleMessage processes a single message, retrying and forwarding it on
+// failure. Everything happens in one consume span that continues the trace
+// propagated in the message headers, so retry and dead-letter messages
+// carry the trace on. It reports whether the message should be marked; a
+// failed message without a retry or dead-letter topic is left unmarked.
+func (c *Consumer) handleMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) (bool, error) {
+    ctx := c.propagator.Extract(session.Context(), tracing.ConsumerHeaders{Msg: message})
+    ctx, span := c.tracer.Start(ctx, "process "+message.Topic,
+        trace.WithSpanKind(trace.SpanKindConsumer),
+        trace.WithAttributes(tracing.MessageAttributes(message.Topic, message.Key)...),
+        trace.WithAttributes(tracing.OffsetAttributes(message.Partition, message.Offset)...))
+
+    attempts, err := c.processWithRetry(ctx, message)
+    defer func() { tracing.EndSpan(span, err) }()
+    if err == nil {
+        return true, nil
     }
 
-    log.Printf("Consumed message from %s[%d]@%d: %s", msg.Topic, msg.Partition, msg.Offset, string(msg.Value))
-
-    // Store in MongoDB if configured
-    if c.mongoCollection != nil {
-        if err := c.storeMessage(message); err != nil {
-            return fmt.Errorf("failed to store message in MongoDB: %w", err)
-        }
+    c.logger.Error("Failed to process message", append(messageAttrs(message), slog.Int("attempts", attempts), slog.Any("error", err))...)
+    if session.Context().Err() == nil && c.reportMessage(message, ErrorProcessing, err) {
+        return false, err
+    }
+    if c.republisher == nil {
+        return false, nil
+    }
+    if session.Context().Err() != nil {
+        return false, session.Context().Err()
+    }
+    if err := c.handleFailure(ctx, message, err, attempts); err != nil {
+        return false, err
     }
+    return true, nil
+}
 
-    return nil
+// stopClaim hides the error caused by the session ending.
+func (c *Consumer) stopClaim(session sarama.ConsumerGroupSession, err error) error {
+    if session.Context().Err() != nil {
+        return nil
+    }
+    return err
 }
 
-func (c *Consumer) storeMessage(msg Message) error {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
+func (c *Consumer) payload() logging.Payload {
+    return logging.Payload{Enabled: c.config.LogPayloads, MaxBytes: c.config.MaxLogPayload}
+}
 
-    _, err := c.mongoCollection.InsertOne(ctx, msg)
+// processMessage makes one attempt at decoding and handling msg. ctx
+// carries the consume span started by handleMessage.
+func (c *Consumer) processMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
+    start := time.Now()
+    ctx, err := c.decode(ctx, msg)
+    if err == nil {
+        err = c.handler.Handle(ctx, msg)
+    }
+    c.metrics.ObserveProcess(msg.Topic, 1, time.Since(start), err)
     if err != nil {
-        return err
+        return fmt.Errorf("handler failed for %s[%d]@%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
     }
-
-    log.Printf("Message stored in MongoDB: %s[%d]@%d", msg.Topic, msg.Partition, msg.Offset)
     return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,121 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+)
+
+// Headers added to messages republished on the retry and dead-letter topics.
+// The original headers are kept as well.
+const (
+    HeaderOriginalTopic     = "x-original-topic"
+    HeaderOriginalPartition = "x-original-partition"
+    HeaderOriginalOffset    = "x-original-offset"
+    HeaderError             = "x-error"
+    HeaderAttempts          = "x-attempts"
+)
+
+// DeadLetterFailurePolicy decides what happens when a failed message cannot
+// be published to the retry or dead-letter topic either.
+type DeadLetterFailurePolicy int
+
+const (
+    // DeadLetterStop leaves the message unmarked and stops consuming the
+    // partition. The session is restarted and the message is redelivered.
+    DeadLetterStop DeadLetterFailurePolicy = iota
+    // DeadLetterSkip logs the failure and marks the message anyway, so it
+    // is lost.
+    DeadLetterSkip
+)
+
+func (c *Consumer) setupRepublisher() error {
+    p, err := producer.NewProducer(producer.Config{
+        Brokers:        c.config.Brokers,
+        Security:       c.config.Security,
+        SaramaOptions:  c.config.SaramaOptions,
+        Logger:         c.logger,
+        LogPayloads:    c.config.LogPayloads,
+        MaxLogPayload:  c.config.MaxLogPayload,
+        Metrics:        c.metrics,
+        TracerProvider: c.config.TracerProvider,
+        Propagator:     c.config.Propagator,
+    })
+    if err != nil {
+        return err
+    }
+
+    c.republisher = p
+    return nil
+}
+
+// failureHeaders copies the headers of msg and adds its origin, the error and
+// the attempt count. Messages that are already being retried keep their
+// original origin and accumulate attempts.
+func failureHeaders(msg *sarama.ConsumerMessage, cause error, attempts int) map[string]string {
+    headers := make(map[string]string, len(msg.Headers)+5)
+    for _, header := range msg.Headers {
+        headers[string(header.Key)] = string(header.Value)
+    }
+    if _, ok := headers[HeaderOriginalTopic]; !ok {
+        headers[HeaderOriginalTopic] = msg.Topic
+        headers[HeaderOriginalPartition] = strconv.FormatInt(int64(msg.Partition), 10)
+        headers[HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+    }
+    if previous, err := strconv.Atoi(headers[HeaderAttempts]); err == nil {
+        attempts += previous
+    }
+    headers[HeaderError] = cause.Error()
+    headers[HeaderAttempts] = strconv.Itoa(attempts)
+    delete(headers, HeaderRetryTier)
+    delete(headers, HeaderRetryNotBefore)
+    return headers
+}
+
+// deadLetter republishes msg on the dead-letter topic with headers describing
+// where it came from and why it failed. It is sent in the span of ctx.
+func (c *Consumer) deadLetter(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) error {
+    record := producer.Record{Key: string(msg.Key), Value: msg.Value, Headers: failureHeaders(msg, cause, attempts)}
+    if _, err := c.republisher.SendRecordContext(ctx, c.config.DeadLetterTopic, record); err != nil {
+        return fmt.Errorf("failed to publish %s[%d]@%d to dead-letter topic %s: %w",
+            msg.Topic, msg.Partition, msg.Offset, c.config.DeadLetterTopic, err)
+    }
+
+    c.logger.Warn("Message sent to dead-letter topic", append(messageAttrs(msg), slog.String("dead_letter_topic", c.config.DeadLetterTopic))...)
+    return nil
+}
+
+// handleFailure routes a message that failed processing to its next retry
+// tier or to the dead-letter topic. It returns nil when the message may be
+// marked and an error when the claim must stop. ctx carries the consume
+// span of the message.
+func (c *Consumer) handleFailure(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) error {
+    var err error
+    forwarded := false
+    if len(c.config.RetryTopics) > 0 && c.config.RetryPolicy.retriable(cause) {
+        forwarded, err = c.forwardRetry(ctx, msg, cause, attempts)
+    }
+    if !forwarded {
+        if c.config.DeadLetterTopic == "" {
+            c.logger.Warn("Dropping message", append(messageAttrs(msg), slog.Int("attempts", attempts), slog.Any("error", cause))...)
+            return nil
+        }
+        err = c.deadLetter(ctx, msg, cause, attempts)
+    }
+    if err == nil {
+        return nil
+    }
+
+    if c.reportMessage(msg, ErrorSink, err) {
+        return err
+    }
+    if c.config.DeadLetterFailurePolicy == DeadLetterSkip {
+        c.logger.Warn("Skipping message", append(messageAttrs(msg), slog.Any("error", err))...)
+        return nil
+    }
+    return err
+}
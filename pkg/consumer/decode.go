@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+type decodedKey struct{}
+
+// DecodedValue returns the value of msg as decoded by
+// Config.ValueDeserializer before the handler was called. It reports false
+// when the consumer has no deserializer.
+func DecodedValue(ctx context.Context, msg *sarama.ConsumerMessage) (any, bool) {
+    values, _ := ctx.Value(decodedKey{}).(map[*sarama.ConsumerMessage]any)
+    value, ok := values[msg]
+    return value, ok
+}
+
+// decode runs the value deserializer over msgs and returns a context that
+// carries the decoded values. A message that cannot be decoded fails like
+// a handler error, so it is retried or dead-lettered without reaching the
+// handler.
+func (c *Consumer) decode(ctx context.Context, msgs ...*sarama.ConsumerMessage) (context.Context, error) {
+    if c.config.ValueDeserializer == nil {
+        return ctx, nil
+    }
+    values := make(map[*sarama.ConsumerMessage]any, len(msgs))
+    for _, msg := range msgs {
+        value, err := c.config.ValueDeserializer.Deserialize(ctx, msg.Topic, msg.Value)
+        if err != nil {
+            return ctx, fmt.Errorf("failed to decode %s[%d]@%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+        }
+        values[msg] = value
+    }
+    return context.WithValue(ctx, decodedKey{}, values), nil
+}
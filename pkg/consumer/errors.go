@@ -0,0 +1,184 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// groupRetryBackoff is the wait before rejoining after a failed group session.
+const groupRetryBackoff = 2 * time.Second
+
+// ErrorKind classifies the errors reported through Config.OnError.
+type ErrorKind int
+
+const (
+    // ErrorProcessing is a message the handler failed to process after all
+    // in-process retries.
+    ErrorProcessing ErrorKind = iota
+    // ErrorSink is a failed write to a store (see SinkError) or to the retry
+    // or dead-letter topic.
+    ErrorSink
+    // ErrorGroup is a broker or group session error.
+    ErrorGroup
+    // ErrorCommit is a rejected offset commit.
+    ErrorCommit
+)
+
+func (k ErrorKind) String() string {
+    switch k {
+    case ErrorProcessing:
+        return "processing"
+    case ErrorSink:
+        return "sink"
+    case ErrorGroup:
+        return "group"
+    case ErrorCommit:
+        return "commit"
+    default:
+        return fmt.Sprintf("ErrorKind(%d)", int(k))
+    }
+}
+
+// ErrorAction is what the consumer does after reporting an error.
+type ErrorAction int
+
+const (
+    // ErrorRetry keeps consuming: failed messages go through the retry and
+    // dead-letter handling, group sessions are rejoined and commits are
+    // retried with the next one.
+    ErrorRetry ErrorAction = iota
+    // ErrorFatal stops the consumer and makes Run return the error.
+    ErrorFatal
+)
+
+// ErrorPolicy maps error kinds to actions. Kinds that are not listed are
+// retried.
+type ErrorPolicy map[ErrorKind]ErrorAction
+
+// Error is a classified consumer error.
+type Error struct {
+    Kind ErrorKind
+    // Topic, Partition and Offset locate the message or partition the error
+    // relates to. Partition and Offset are -1 when unknown.
+    Topic     string
+    Partition int32
+    Offset    int64
+    Err       error
+}
+
+func (e *Error) Error() string {
+    switch {
+    case e.Offset >= 0:
+        return fmt.Sprintf("%s error on %s[%d]@%d: %v", e.Kind, e.Topic, e.Partition, e.Offset, e.Err)
+    case e.Partition >= 0:
+        return fmt.Sprintf("%s error on %s[%d]: %v", e.Kind, e.Topic, e.Partition, e.Err)
+    default:
+        return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+    }
+}
+
+func (e *Error) Unwrap() error {
+    return e.Err
+}
+
+type sinkError struct {
+    err error
+}
+
+func (e *sinkError) Error() string { return e.err.Error() }
+func (e *sinkError) Unwrap() error { return e.err }
+
+// SinkError marks err as a failed write to an external store, so it is
+// reported as ErrorSink rather than ErrorProcessing. It returns nil for a nil
+// error.
+func SinkError(err error) error {
+    if err == nil {
+        return nil
+    }
+    return &sinkError{err: err}
+}
+
+// reportMessage reports an error about msg and whether it is fatal.
+func (c *Consumer) reportMessage(msg *sarama.ConsumerMessage, kind ErrorKind, err error) bool {
+    if kind == ErrorProcessing {
+        var sink *sinkError
+        if errors.As(err, &sink) {
+            kind = ErrorSink
+        }
+    }
+    return c.report(&Error{Kind: kind, Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, Err: err})
+}
+
+// reportGroupError reports an error returned by or received from the
+// consumer group and whether it is fatal.
+func (c *Consumer) reportGroupError(err error) bool {
+    e := &Error{Kind: ErrorGroup, Partition: -1, Offset: -1, Err: err}
+    var consumerErr *sarama.ConsumerError
+    if errors.As(err, &consumerErr) {
+        e.Topic = consumerErr.Topic
+        e.Partition = consumerErr.Partition
+    }
+    if isCommitError(err) {
+        e.Kind = ErrorCommit
+    }
+    return c.report(e)
+}
+
+// isCommitError recognises rejected commits by where they come from.
+// sarama's offset manager reports them per partition with the error code of
+// the OffsetCommit response. Fetches never return group error codes, so one
+// attached to a partition comes from a commit, while the same codes from
+// joins and heartbeats are reported without a partition.
+func isCommitError(err error) bool {
+    var consumerErr *sarama.ConsumerError
+    if !errors.As(err, &consumerErr) || consumerErr.Partition < 0 {
+        return false
+    }
+    var kerr sarama.KError
+    if !errors.As(consumerErr.Err, &kerr) {
+        return false
+    }
+    switch kerr {
+    case sarama.ErrOffsetMetadataTooLarge,
+        sarama.ErrInvalidCommitOffsetSize,
+        sarama.ErrIllegalGeneration,
+        sarama.ErrUnknownMemberId,
+        sarama.ErrRebalanceInProgress,
+        sarama.ErrFencedInstancedId,
+        sarama.ErrGroupAuthorizationFailed:
+        return true
+    default:
+        return false
+    }
+}
+
+// report hands e to the OnError callback and stops the consumer if the
+// policy makes it fatal.
+func (c *Consumer) report(e *Error) bool {
+    if c.config.OnError != nil {
+        c.config.OnError(e)
+    }
+    if c.config.ErrorPolicy[e.Kind] != ErrorFatal {
+        return false
+    }
+
+    c.logger.Error("Stopping consumer on fatal error", slog.String("kind", e.Kind.String()), slog.Any("error", e))
+    c.fatalOnce.Do(func() {
+        c.fatalErr = e
+        c.cancel()
+    })
+    return true
+}
+
+// drainGroupErrors reports the errors of the consumer group until it is
+// closed.
+func (c *Consumer) drainGroupErrors() {
+    for err := range c.client.Errors() {
+        c.logger.Error("Consumer group error", slog.Any("error", err))
+        c.reportGroupError(err)
+    }
+}
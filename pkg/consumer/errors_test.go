@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestIsCommitError(t *testing.T) {
+    partitionErr := func(err error) error {
+        return &sarama.ConsumerError{Topic: "orders", Partition: 3, Err: err}
+    }
+    tests := []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"metadata too large", partitionErr(sarama.ErrOffsetMetadataTooLarge), true},
+        {"invalid commit size", partitionErr(sarama.ErrInvalidCommitOffsetSize), true},
+        {"stale generation", partitionErr(sarama.ErrIllegalGeneration), true},
+        {"unknown member", partitionErr(sarama.ErrUnknownMemberId), true},
+        {"wrapped", fmt.Errorf("group: %w", partitionErr(sarama.ErrRebalanceInProgress)), true},
+        {"heartbeat error without partition", sarama.ErrRebalanceInProgress, false},
+        {"group error without partition", &sarama.ConsumerError{Partition: -1, Err: sarama.ErrUnknownMemberId}, false},
+        {"incomplete fetch response", partitionErr(sarama.ErrIncompleteResponse), false},
+        {"fetch error", partitionErr(sarama.ErrOffsetOutOfRange), false},
+        {"handler error", partitionErr(errors.New("handler failed")), false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := isCommitError(tt.err); got != tt.want {
+                t.Errorf("isCommitError(%v) = %t, want %t", tt.err, got, tt.want)
+            }
+        })
+    }
+}
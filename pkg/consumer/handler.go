@@ -0,0 +1,70 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+)
+
+// Handler processes a single message claimed by the consumer. The offset is
+// only marked when Handle returns nil.
+type Handler interface {
+    Handle(ctx context.Context, msg *sarama.ConsumerMessage) error
+}
+
+// HandlerFunc adapts an ordinary function to the Handler interface.
+type HandlerFunc func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// Handle calls f(ctx, msg).
+func (f HandlerFunc) Handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+    return f(ctx, msg)
+}
+
+// LogHandler returns a Handler that only logs each consumed message at info
+// level, with its payload if enabled. It is used when neither a Handler nor
+// MongoDB is configured.
+func LogHandler(logger *slog.Logger, payload logging.Payload) Handler {
+    logger = logging.OrDefault(logger)
+    return HandlerFunc(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+        logger.InfoContext(ctx, "Message consumed", append(messageAttrs(msg), payload.Attr(msg.Value))...)
+        return nil
+    })
+}
+
+// messageAttrs returns the log attributes identifying msg.
+func messageAttrs(msg *sarama.ConsumerMessage) []any {
+    return []any{
+        slog.String("topic", msg.Topic),
+        slog.Int("partition", int(msg.Partition)),
+        slog.Int64("offset", msg.Offset),
+        slog.String("key", string(msg.Key)),
+    }
+}
+
+// NewMessage converts a sarama message into a Message, parsing the value as
+// JSON if possible and otherwise keeping it as a string.
+func NewMessage(msg *sarama.ConsumerMessage) Message {
+    var value interface{}
+    if err := json.Unmarshal(msg.Value, &value); err != nil {
+        value = string(msg.Value)
+    }
+
+    // Convert headers
+    headers := make(map[string]string)
+    for _, header := range msg.Headers {
+        headers[string(header.Key)] = string(header.Value)
+    }
+
+    return Message{
+        Topic:     msg.Topic,
+        Partition: msg.Partition,
+        Offset:    msg.Offset,
+        Key:       string(msg.Key),
+        Value:     value,
+        Headers:   headers,
+        Timestamp: msg.Timestamp,
+    }
+}
@@ -0,0 +1,290 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/metrics"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoWriteTimeout bounds a single insert or batch write.
+const mongoWriteTimeout = 5 * time.Second
+
+// MongoConfig describes where the Mongo handler stores consumed messages.
+type MongoConfig struct {
+    URI        string
+    Database   string
+    Collection string
+    // WriteConcern is "majority", a number of nodes or a tag set name.
+    // Empty keeps the server default.
+    WriteConcern string
+
+    // Idempotent stores messages with a deterministic _id and upserts them,
+    // so replayed messages do not create duplicate documents.
+    Idempotent bool
+    // IDKey selects the _id of idempotent documents: empty for
+    // "off:<topic>:<partition>:<offset>", "key" for "key:<topic>:<key>" or
+    // "header:<name>" for "header:<topic>:<value>" with the value of a
+    // header. Messages without the key or header fall back to the offset
+    // form.
+    //
+    // With "key" or a header, messages sharing an _id are compacted into one
+    // document holding the last message written, not the one with the
+    // highest offset: replaying older offsets, e.g. after a seek or an offset
+    // reset, overwrites newer data until the replay catches up.
+    IDKey string
+
+    // Logger receives the handler's logs. Defaults to slog.Default().
+    Logger *slog.Logger
+    // Metrics, if set, records write latencies under the "mongo" sink.
+    Metrics *metrics.Metrics
+    // TracerProvider creates the spans around writes, children of the span
+    // in the write context. Defaults to the global provider.
+    TracerProvider trace.TracerProvider
+}
+
+// MongoHandler is the built-in Handler that stores every consumed message as
+// a document in a MongoDB collection. It implements BatchHandler with
+// unordered InsertMany.
+type MongoHandler struct {
+    config     MongoConfig
+    logger     *slog.Logger
+    tracer     trace.Tracer
+    client     *mongo.Client
+    collection *mongo.Collection
+}
+
+// NewMongoHandler connects to MongoDB and verifies the connection.
+func NewMongoHandler(ctx context.Context, config MongoConfig) (*MongoHandler, error) {
+    if err := validateIDKey(config.IDKey); err != nil {
+        return nil, err
+    }
+
+    collectionOptions := options.Collection()
+    if config.WriteConcern != "" {
+        wc, err := parseWriteConcern(config.WriteConcern)
+        if err != nil {
+            return nil, err
+        }
+        collectionOptions.SetWriteConcern(wc)
+    }
+
+    clientOptions := options.Client().ApplyURI(config.URI)
+    client, err := mongo.Connect(ctx, clientOptions)
+    if err != nil {
+        return nil, err
+    }
+
+    // Test connection
+    if err := client.Ping(ctx, nil); err != nil {
+        client.Disconnect(ctx)
+        return nil, err
+    }
+
+    h := &MongoHandler{
+        config:     config,
+        logger:     logging.OrDefault(config.Logger),
+        tracer:     tracing.Tracer(config.TracerProvider),
+        client:     client,
+        collection: client.Database(config.Database).Collection(config.Collection, collectionOptions),
+    }
+    if config.Idempotent {
+        if err := h.ensureIndexes(ctx); err != nil {
+            client.Disconnect(ctx)
+            return nil, err
+        }
+    }
+
+    h.logger.Info("Connected to MongoDB", slog.String("database", config.Database), slog.String("collection", config.Collection))
+    return h, nil
+}
+
+func validateIDKey(idKey string) error {
+    if idKey == "" || idKey == "key" {
+        return nil
+    }
+    if name, ok := strings.CutPrefix(idKey, "header:"); ok && name != "" {
+        return nil
+    }
+    return fmt.Errorf("invalid Mongo ID key %q", idKey)
+}
+
+// ensureIndexes creates the unique index backing idempotent storage. Every
+// Kafka record is identified by its topic, partition and offset whatever
+// the _id is derived from. The index cannot be built on a collection that
+// already holds duplicates, such as one written without Idempotent; they
+// have to be removed first.
+func (h *MongoHandler) ensureIndexes(ctx context.Context) error {
+    _, err := h.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{Key: "topic", Value: 1}, {Key: "partition", Value: 1}, {Key: "offset", Value: 1}},
+        Options: options.Index().SetUnique(true).SetName("topic_partition_offset"),
+    })
+    if mongo.IsDuplicateKeyError(err) {
+        return fmt.Errorf("failed to create unique index: %s holds several documents for the same topic, partition and offset, remove the duplicates first: %w", h.config.Collection, err)
+    }
+    if err != nil {
+        return fmt.Errorf("failed to create unique index: %w", err)
+    }
+    return nil
+}
+
+// document converts msg into the stored form, with the value decoded by
+// the consumer if there is one, and assigns its _id in idempotent mode.
+func (h *MongoHandler) document(ctx context.Context, msg *sarama.ConsumerMessage) Message {
+    doc := NewMessage(msg)
+    if value, ok := DecodedValue(ctx, msg); ok {
+        doc.Value = value
+    }
+    if !h.config.Idempotent {
+        return doc
+    }
+
+    // Each form has its own prefix and topics cannot contain ':', so IDs
+    // of different forms and topics never collide.
+    doc.ID = fmt.Sprintf("off:%s:%d:%d", msg.Topic, msg.Partition, msg.Offset)
+    if h.config.IDKey == "key" && len(msg.Key) > 0 {
+        doc.ID = "key:" + msg.Topic + ":" + string(msg.Key)
+    } else if name, ok := strings.CutPrefix(h.config.IDKey, "header:"); ok {
+        if value, ok := doc.Headers[name]; ok && value != "" {
+            doc.ID = "header:" + msg.Topic + ":" + value
+        }
+    }
+    return doc
+}
+
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+    if value == "majority" {
+        return writeconcern.Majority(), nil
+    }
+    if w, err := strconv.Atoi(value); err == nil {
+        if w < 0 {
+            return nil, fmt.Errorf("invalid write concern %q", value)
+        }
+        return &writeconcern.WriteConcern{W: w}, nil
+    }
+    return writeconcern.Custom(value), nil
+}
+
+// Handle implements Handler. Write failures are returned as SinkError.
+func (h *MongoHandler) Handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+    return SinkError(h.storeMessage(ctx, h.document(ctx, msg)))
+}
+
+// HandleBatch implements BatchHandler
+func (h *MongoHandler) HandleBatch(ctx context.Context, msgs []*sarama.ConsumerMessage) error {
+    ctx, cancel := context.WithTimeout(ctx, mongoWriteTimeout)
+    defer cancel()
+
+    operation := "insertMany"
+    if h.config.Idempotent {
+        operation = "bulkWrite"
+    }
+    ctx, span := h.startSpan(ctx, operation, len(msgs))
+    start := time.Now()
+    var err error
+    if h.config.Idempotent {
+        models := make([]mongo.WriteModel, len(msgs))
+        for i, msg := range msgs {
+            doc := h.document(ctx, msg)
+            models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": doc.ID}).SetReplacement(doc).SetUpsert(true)
+        }
+        _, err = h.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+    } else {
+        docs := make([]interface{}, len(msgs))
+        for i, msg := range msgs {
+            docs[i] = h.document(ctx, msg)
+        }
+        _, err = h.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+    }
+    h.config.Metrics.ObserveSinkWrite("mongo", len(msgs), time.Since(start), err)
+    tracing.EndSpan(span, err)
+    if err != nil {
+        return SinkError(bulkWriteError(err))
+    }
+
+    last := msgs[len(msgs)-1]
+    h.logger.DebugContext(ctx, "Batch stored in MongoDB",
+        slog.String("topic", last.Topic),
+        slog.Int("partition", int(last.Partition)),
+        slog.Int64("first_offset", msgs[0].Offset),
+        slog.Int64("last_offset", last.Offset),
+        slog.Int("size", len(msgs)))
+    return nil
+}
+
+// bulkWriteError narrows a failed unordered write to the rejected
+// documents, as a *BatchError, so only their messages are retried. Without a
+// write concern error the documents that are not listed were written.
+func bulkWriteError(err error) error {
+    var bulkErr mongo.BulkWriteException
+    if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+        return err
+    }
+    failed := make([]int, len(bulkErr.WriteErrors))
+    for i, writeErr := range bulkErr.WriteErrors {
+        failed[i] = writeErr.Index
+    }
+    return &BatchError{Failed: failed, Err: err}
+}
+
+func (h *MongoHandler) storeMessage(ctx context.Context, msg Message) error {
+    ctx, cancel := context.WithTimeout(ctx, mongoWriteTimeout)
+    defer cancel()
+
+    operation := "insert"
+    if h.config.Idempotent {
+        operation = "replaceOne"
+    }
+    ctx, span := h.startSpan(ctx, operation, 1)
+    start := time.Now()
+    var err error
+    if h.config.Idempotent {
+        _, err = h.collection.ReplaceOne(ctx, bson.M{"_id": msg.ID}, msg, options.Replace().SetUpsert(true))
+    } else {
+        _, err = h.collection.InsertOne(ctx, msg)
+    }
+    h.config.Metrics.ObserveSinkWrite("mongo", 1, time.Since(start), err)
+    tracing.EndSpan(span, err)
+    if err != nil {
+        return err
+    }
+
+    h.logger.DebugContext(ctx, "Message stored in MongoDB",
+        slog.String("topic", msg.Topic),
+        slog.Int("partition", int(msg.Partition)),
+        slog.Int64("offset", msg.Offset),
+        slog.String("key", msg.Key))
+    return nil
+}
+
+// startSpan starts the client span of a write of n documents.
+func (h *MongoHandler) startSpan(ctx context.Context, operation string, n int) (context.Context, trace.Span) {
+    return h.tracer.Start(ctx, operation+" "+h.config.Collection,
+        trace.WithSpanKind(trace.SpanKindClient),
+        trace.WithAttributes(
+            attribute.String("db.system", "mongodb"),
+            attribute.String("db.namespace", h.config.Database),
+            attribute.String("db.collection.name", h.config.Collection),
+            attribute.String("db.operation.name", operation),
+            attribute.Int("db.operation.batch.size", n),
+        ))
+}
+
+// Close disconnects from MongoDB.
+func (h *MongoHandler) Close(ctx context.Context) error {
+    return h.client.Disconnect(ctx)
+}
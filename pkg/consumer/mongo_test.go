@@ -0,0 +1,40 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestDocumentID(t *testing.T) {
+    msg := func(topic string, partition int32, offset int64, key, header string) *sarama.ConsumerMessage {
+        m := &sarama.ConsumerMessage{Topic: topic, Partition: partition, Offset: offset, Key: []byte(key)}
+        if header != "" {
+            m.Headers = []*sarama.RecordHeader{{Key: []byte("idempotency-key"), Value: []byte(header)}}
+        }
+        return m
+    }
+    tests := []struct {
+        name  string
+        idKey string
+        msg   *sarama.ConsumerMessage
+        want  string
+    }{
+        {"offset", "", msg("t", 0, 5, "0-5", ""), "off:t:0:5"},
+        {"key", "key", msg("t", 0, 5, "0-5", ""), "key:t:0-5"},
+        {"key with colons", "key", msg("t", 0, 5, "a:b", ""), "key:t:a:b"},
+        {"missing key", "key", msg("t", 1, 9, "", ""), "off:t:1:9"},
+        {"header", "header:idempotency-key", msg("t", 0, 5, "", "order-1"), "header:t:order-1"},
+        {"header on another topic", "header:idempotency-key", msg("u", 0, 5, "", "order-1"), "header:u:order-1"},
+        {"missing header", "header:idempotency-key", msg("t", 2, 3, "", ""), "off:t:2:3"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h := &MongoHandler{config: MongoConfig{Idempotent: true, IDKey: tt.idKey}}
+            if got := h.document(context.Background(), tt.msg).ID; got != tt.want {
+                t.Errorf("_id = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
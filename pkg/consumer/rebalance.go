@@ -0,0 +1,67 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+)
+
+// Assignment describes the partitions claimed by this member in one group
+// generation. Session can be used to mark or commit offsets from a hook.
+type Assignment struct {
+    GenerationID int32
+    MemberID     string
+    Claims       map[string][]int32
+    Session      sarama.ConsumerGroupSession
+}
+
+// RebalanceHook is called when partitions are assigned to or revoked from
+// the consumer. The context outlives the group session.
+type RebalanceHook func(ctx context.Context, assignment Assignment) error
+
+func newAssignment(session sarama.ConsumerGroupSession) Assignment {
+    return Assignment{
+        GenerationID: session.GenerationID(),
+        MemberID:     session.MemberID(),
+        Claims:       session.Claims(),
+        Session:      session,
+    }
+}
+
+// Setup implements sarama.ConsumerGroupHandler. It runs at the start of every
+// generation, before any claim is consumed.
+func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
+    if err := c.applySeeks(session); err != nil {
+        return err
+    }
+
+    c.logger.Info("Partitions assigned", slog.Int("generation", int(session.GenerationID())), slog.Any("claims", session.Claims()))
+    if c.config.OnAssigned != nil {
+        if err := c.config.OnAssigned(context.WithoutCancel(session.Context()), newAssignment(session)); err != nil {
+            return fmt.Errorf("OnAssigned hook failed: %w", err)
+        }
+    }
+
+    c.readyOnce.Do(func() { close(c.ready) })
+    return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler. It runs at the end of
+// every generation, once every claim has stopped and before the final offset
+// commit.
+func (c *Consumer) Cleanup(session sarama.ConsumerGroupSession) error {
+    c.logger.Info("Partitions revoked", slog.Int("generation", int(session.GenerationID())), slog.Any("claims", session.Claims()))
+    for topic, partitions := range session.Claims() {
+        for _, partition := range partitions {
+            c.metrics.DeleteLag(topic, partition)
+        }
+    }
+    if c.config.OnRevoked != nil {
+        if err := c.config.OnRevoked(context.WithoutCancel(session.Context()), newAssignment(session)); err != nil {
+            return fmt.Errorf("OnRevoked hook failed: %w", err)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,226 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+)
+
+// Headers used by the non-blocking retry topics.
+const (
+    HeaderRetryTier      = "x-retry-tier"
+    HeaderRetryNotBefore = "x-retry-not-before"
+)
+
+// RetryPolicy controls in-process retries of a failed message before it is
+// forwarded to a retry topic or the dead-letter topic.
+type RetryPolicy struct {
+    // MaxAttempts is the total number of attempts, including the first.
+    // Values below 2 disable in-process retries.
+    MaxAttempts int
+    // InitialBackoff is the wait before the first retry. It doubles on
+    // every further attempt up to MaxBackoff.
+    InitialBackoff time.Duration
+    MaxBackoff     time.Duration
+    // Jitter randomises each backoff by up to this fraction, e.g. 0.2 for
+    // +/-20%.
+    Jitter float64
+    // RetriableErrors lists the errors worth retrying, matched with
+    // errors.Is. When empty every error is retriable.
+    RetriableErrors []error
+}
+
+// RetryTopic is one tier of the non-blocking retry chain. Messages are
+// forwarded to "<topic>.retry.<Suffix>" and are not processed again before
+// Delay has elapsed.
+type RetryTopic struct {
+    Suffix string
+    Delay  time.Duration
+}
+
+// DefaultRetryTopics is a one minute tier followed by a ten minute tier.
+var DefaultRetryTopics = []RetryTopic{
+    {Suffix: "1m", Delay: time.Minute},
+    {Suffix: "10m", Delay: 10 * time.Minute},
+}
+
+// RetryTopicName returns the name of the retry tier topic for topic.
+func RetryTopicName(topic string, tier RetryTopic) string {
+    return topic + ".retry." + tier.Suffix
+}
+
+func (p RetryPolicy) retriable(err error) bool {
+    if len(p.RetriableErrors) == 0 {
+        return true
+    }
+    for _, target := range p.RetriableErrors {
+        if errors.Is(err, target) {
+            return true
+        }
+    }
+    return false
+}
+
+// backoff returns the wait before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+    d := p.InitialBackoff
+    for i := 1; i < retry && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+        d *= 2
+    }
+    if p.MaxBackoff > 0 && d > p.MaxBackoff {
+        d = p.MaxBackoff
+    }
+    if p.Jitter > 0 {
+        d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+    }
+    return d
+}
+
+// subscribedTopics returns the configured topics followed by their retry
+// tier topics.
+func (c *Consumer) subscribedTopics() []string {
+    topics := append([]string(nil), c.config.Topics...)
+    for _, topic := range c.config.Topics {
+        for _, tier := range c.config.RetryTopics {
+            topics = append(topics, RetryTopicName(topic, tier))
+        }
+    }
+    return topics
+}
+
+// processWithRetry runs the handler, retrying retriable errors according to
+// the retry policy. It returns the number of attempts made.
+func (c *Consumer) processWithRetry(ctx context.Context, msg *sarama.ConsumerMessage) (int, error) {
+    policy := c.config.RetryPolicy
+    attempts := 0
+    for {
+        attempts++
+        err := c.processMessage(ctx, msg)
+        if err == nil {
+            return attempts, nil
+        }
+        if attempts >= policy.MaxAttempts || !policy.retriable(err) {
+            return attempts, err
+        }
+
+        wait := policy.backoff(attempts)
+        c.logger.Warn("Retrying message",
+            append(messageAttrs(msg), slog.Int("attempt", attempts), slog.Duration("backoff", wait), slog.Any("error", err))...)
+        if err := sleep(ctx, wait); err != nil {
+            return attempts, err
+        }
+    }
+}
+
+// waitNotBefore delays a message read from a retry topic until its
+// not-before time has passed. Later messages of the tier are not due before
+// it anyway. The partition is paused meanwhile, so sarama stops fetching it
+// and the other partitions of its broker are not held up for
+// MaxProcessingTime. The wait ends with ctx, e.g. on a rebalance, and the
+// message is then redelivered.
+func (c *Consumer) waitNotBefore(ctx context.Context, msg *sarama.ConsumerMessage) error {
+    value, ok := header(msg, HeaderRetryNotBefore)
+    if !ok {
+        return nil
+    }
+    notBefore, err := time.Parse(time.RFC3339Nano, value)
+    if err != nil {
+        return nil
+    }
+    wait := time.Until(notBefore)
+    if wait <= 0 {
+        return nil
+    }
+
+    partitions := map[string][]int32{msg.Topic: {msg.Partition}}
+    c.client.Pause(partitions)
+    defer c.client.Resume(partitions)
+    return sleep(ctx, wait)
+}
+
+// checkRetryTopics fails when a retry tier topic does not exist, rather than
+// on the first message forwarded to it.
+func (c *Consumer) checkRetryTopics() error {
+    if err := c.kafka.RefreshMetadata(); err != nil {
+        return fmt.Errorf("failed to refresh metadata: %w", err)
+    }
+    topics, err := c.kafka.Topics()
+    if err != nil {
+        return fmt.Errorf("failed to list topics: %w", err)
+    }
+    existing := make(map[string]bool, len(topics))
+    for _, topic := range topics {
+        existing[topic] = true
+    }
+
+    var missing []string
+    for _, topic := range c.config.Topics {
+        for _, tier := range c.config.RetryTopics {
+            if name := RetryTopicName(topic, tier); !existing[name] {
+                missing = append(missing, name)
+            }
+        }
+    }
+    if len(missing) > 0 {
+        return fmt.Errorf("retry topics do not exist: %s", strings.Join(missing, ", "))
+    }
+    return nil
+}
+
+// forwardRetry publishes a failed message to its next retry tier. It returns
+// false when the message has already been through every tier. It is sent in
+// the span of ctx.
+func (c *Consumer) forwardRetry(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) (bool, error) {
+    tier := 0
+    if value, ok := header(msg, HeaderRetryTier); ok {
+        tier, _ = strconv.Atoi(value)
+    }
+    if tier >= len(c.config.RetryTopics) {
+        return false, nil
+    }
+
+    next := c.config.RetryTopics[tier]
+    headers := failureHeaders(msg, cause, attempts)
+    headers[HeaderRetryTier] = strconv.Itoa(tier + 1)
+    headers[HeaderRetryNotBefore] = time.Now().Add(next.Delay).UTC().Format(time.RFC3339Nano)
+
+    topic := RetryTopicName(headers[HeaderOriginalTopic], next)
+    record := producer.Record{Key: string(msg.Key), Value: msg.Value, Headers: headers}
+    if _, err := c.republisher.SendRecordContext(ctx, topic, record); err != nil {
+        return true, fmt.Errorf("failed to publish %s[%d]@%d to retry topic %s: %w", msg.Topic, msg.Partition, msg.Offset, topic, err)
+    }
+
+    c.logger.Info("Message sent to retry topic", append(messageAttrs(msg), slog.String("retry_topic", topic))...)
+    return true, nil
+}
+
+func header(msg *sarama.ConsumerMessage, key string) (string, bool) {
+    for _, h := range msg.Headers {
+        if string(h.Key) == key {
+            return string(h.Value), true
+        }
+    }
+    return "", false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+    if d <= 0 {
+        return nil
+    }
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-timer.C:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
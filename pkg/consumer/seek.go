@@ -0,0 +1,158 @@
+package consumer
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// StartPosition is where a group without committed offsets starts consuming.
+type StartPosition int
+
+const (
+    // StartNewest only consumes messages produced after the group joined.
+    StartNewest StartPosition = iota
+    // StartOldest consumes every retained message.
+    StartOldest
+    // StartAtTime consumes from the first message at or after
+    // Config.StartTime.
+    StartAtTime
+)
+
+type topicPartition struct {
+    topic     string
+    partition int32
+}
+
+// Seek moves a partition to offset. It takes effect when the partition is
+// next claimed; the current session is restarted so that happens right away.
+// The new position is committed with the group's next offset commit.
+func (c *Consumer) Seek(topic string, partition int32, offset int64) {
+    c.seekMu.Lock()
+    c.seeks[topicPartition{topic, partition}] = offset
+    c.seekMu.Unlock()
+
+    c.logger.Info("Seeking partition", slog.String("topic", topic), slog.Int("partition", int(partition)), slog.Int64("offset", offset))
+    c.restartSession()
+}
+
+// SeekToTime moves every partition claimed from now on to the first message
+// at or after t, or to the end of the partition when there is none. The
+// current session is restarted so it takes effect right away.
+func (c *Consumer) SeekToTime(t time.Time) {
+    c.seekMu.Lock()
+    c.seekTime = t
+    c.seekMu.Unlock()
+
+    c.logger.Info("Seeking to time", slog.Time("time", t))
+    c.restartSession()
+}
+
+// restartSession ends the current group session, if any. The consume loop
+// then rejoins and Setup applies the pending seeks.
+func (c *Consumer) restartSession() {
+    c.seekMu.Lock()
+    cancel := c.sessionCancel
+    c.seekMu.Unlock()
+    if cancel != nil {
+        cancel()
+    }
+}
+
+// applySeeks resets the claimed partitions to pending Seek/SeekToTime
+// positions and places partitions without a committed offset at StartTime.
+func (c *Consumer) applySeeks(session sarama.ConsumerGroupSession) error {
+    c.seekMu.Lock()
+    defer c.seekMu.Unlock()
+
+    var uncommitted map[topicPartition]bool
+    if c.config.StartPosition == StartAtTime {
+        var err error
+        if uncommitted, err = c.uncommittedPartitions(session.Claims()); err != nil {
+            return err
+        }
+    }
+
+    for topic, partitions := range session.Claims() {
+        for _, partition := range partitions {
+            tp := topicPartition{topic, partition}
+
+            if offset, ok := c.seeks[tp]; ok {
+                moveOffset(session, topic, partition, offset)
+                delete(c.seeks, tp)
+                continue
+            }
+
+            var at time.Time
+            switch {
+            case !c.seekTime.IsZero():
+                at = c.seekTime
+            case uncommitted[tp]:
+                at = c.config.StartTime
+            default:
+                continue
+            }
+            offset, err := c.offsetForTime(topic, partition, at)
+            if err != nil {
+                return err
+            }
+            moveOffset(session, topic, partition, offset)
+        }
+    }
+
+    // A time seek applies to the partitions claimed in this generation.
+    c.seekTime = time.Time{}
+    return nil
+}
+
+// moveOffset sets the next offset of a claimed partition. sarama's offset
+// manager only applies ResetOffset at or below its current offset (-1
+// without a commit) and MarkOffset above it, so exactly one of the two
+// takes effect, whichever way the partition moves.
+func moveOffset(session sarama.ConsumerGroupSession, topic string, partition int32, offset int64) {
+    session.ResetOffset(topic, partition, offset, "")
+    session.MarkOffset(topic, partition, offset, "")
+}
+
+// offsetForTime returns the offset of the first message at or after t, or
+// the log-end offset when there is none.
+func (c *Consumer) offsetForTime(topic string, partition int32, t time.Time) (int64, error) {
+    offset, err := c.kafka.GetOffset(topic, partition, t.UnixMilli())
+    if err != nil {
+        return 0, fmt.Errorf("failed to get offset of %s[%d] at %v: %w", topic, partition, t, err)
+    }
+    if offset < 0 {
+        if offset, err = c.kafka.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+            return 0, fmt.Errorf("failed to get offset of %s[%d]: %w", topic, partition, err)
+        }
+    }
+    return offset, nil
+}
+
+// uncommittedPartitions returns the claimed partitions the group has no
+// committed offset for.
+func (c *Consumer) uncommittedPartitions(claims map[string][]int32) (map[topicPartition]bool, error) {
+    coordinator, err := c.kafka.Coordinator(c.config.ConsumerGroup)
+    if err != nil {
+        return nil, fmt.Errorf("failed to find group coordinator: %w", err)
+    }
+
+    req := sarama.NewOffsetFetchRequest(c.kafka.Config().Version, c.config.ConsumerGroup, claims)
+    resp, err := coordinator.FetchOffset(req)
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+    }
+
+    uncommitted := map[topicPartition]bool{}
+    for topic, partitions := range claims {
+        for _, partition := range partitions {
+            block := resp.GetBlock(topic, partition)
+            if block == nil || block.Offset < 0 {
+                uncommitted[topicPartition{topic, partition}] = true
+            }
+        }
+    }
+    return uncommitted, nil
+}
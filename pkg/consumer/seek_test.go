@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeSession is a sarama.ConsumerGroupSession whose offsets follow the
+// rules of sarama's partition offset manager: ResetOffset only moves at or
+// below the current offset and MarkOffset only above it.
+type fakeSession struct {
+    claims  map[string][]int32
+    offsets map[topicPartition]int64
+}
+
+func (s *fakeSession) Claims() map[string][]int32 { return s.claims }
+func (s *fakeSession) MemberID() string           { return "member" }
+func (s *fakeSession) GenerationID() int32        { return 1 }
+func (s *fakeSession) Commit()                    {}
+func (s *fakeSession) Context() context.Context   { return context.Background() }
+
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+    if tp := (topicPartition{topic, partition}); offset > s.offsets[tp] {
+        s.offsets[tp] = offset
+    }
+}
+
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+    if tp := (topicPartition{topic, partition}); offset <= s.offsets[tp] {
+        s.offsets[tp] = offset
+    }
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+    s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func TestApplySeeks(t *testing.T) {
+    const (
+        topic = "orders"
+        group = "group"
+    )
+    startTime := time.UnixMilli(1_700_000_000_000)
+
+    broker := sarama.NewMockBroker(t, 1)
+    defer broker.Close()
+    metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+    for partition := int32(0); partition < 3; partition++ {
+        metadata.SetLeader(topic, partition, broker.BrokerID())
+    }
+    broker.SetHandlerByMap(map[string]sarama.MockResponse{
+        "MetadataRequest":        metadata,
+        "FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).SetCoordinator(sarama.CoordinatorGroup, group, broker),
+        "OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+            SetOffset(group, topic, 0, 10, "", sarama.ErrNoError).
+            SetOffset(group, topic, 1, 10, "", sarama.ErrNoError).
+            SetOffset(group, topic, 2, -1, "", sarama.ErrNoError),
+        "OffsetRequest": sarama.NewMockOffsetResponse(t).SetOffset(topic, 2, startTime.UnixMilli(), 42),
+    })
+
+    client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer client.Close()
+
+    c := &Consumer{
+        config: Config{ConsumerGroup: group, StartPosition: StartAtTime, StartTime: startTime},
+        logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+        kafka:  client,
+        seeks: map[topicPartition]int64{
+            {topic, 0}: 20,
+            {topic, 1}: 5,
+        },
+    }
+    session := &fakeSession{
+        claims: map[string][]int32{topic: {0, 1, 2}},
+        offsets: map[topicPartition]int64{
+            {topic, 0}: 10,
+            {topic, 1}: 10,
+            {topic, 2}: -1,
+        },
+    }
+
+    if err := c.applySeeks(session); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name      string
+        partition int32
+        want      int64
+    }{
+        {"forward seek", 0, 20},
+        {"backward seek", 1, 5},
+        {"uncommitted at start time", 2, 42},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := session.offsets[topicPartition{topic, tt.partition}]; got != tt.want {
+                t.Errorf("offset of partition %d = %d, want %d", tt.partition, got, tt.want)
+            }
+        })
+    }
+    if len(c.seeks) != 0 {
+        t.Errorf("pending seeks = %v, want none", c.seeks)
+    }
+}
+
+func TestMoveOffset(t *testing.T) {
+    tests := []struct {
+        name    string
+        current int64
+        offset  int64
+    }{
+        {"forward", 10, 20},
+        {"backward", 10, 5},
+        {"same", 10, 10},
+        {"uncommitted", -1, 7},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tp := topicPartition{"orders", 0}
+            session := &fakeSession{offsets: map[topicPartition]int64{tp: tt.current}}
+            moveOffset(session, tp.topic, tp.partition, tt.offset)
+            if got := session.offsets[tp]; got != tt.offset {
+                t.Errorf("offset = %d, want %d", got, tt.offset)
+            }
+        })
+    }
+}
@@ -0,0 +1,50 @@
+package kafkaconfig
+
+import (
+	"os"
+	"strconv"
+)
+
+// Environment variables read by SecurityFromEnv.
+const (
+    EnvTLSEnabled            = "RAN_KAFKA_TLS_ENABLED"
+    EnvTLSCAFile             = "RAN_KAFKA_TLS_CA_FILE"
+    EnvTLSCertFile           = "RAN_KAFKA_TLS_CERT_FILE"
+    EnvTLSKeyFile            = "RAN_KAFKA_TLS_KEY_FILE"
+    EnvTLSInsecureSkipVerify = "RAN_KAFKA_TLS_INSECURE_SKIP_VERIFY"
+    EnvTLSServerName         = "RAN_KAFKA_TLS_SERVER_NAME"
+    EnvSASLMechanism         = "RAN_KAFKA_SASL_MECHANISM"
+    EnvSASLUsername          = "RAN_KAFKA_SASL_USERNAME"
+    EnvSASLPassword          = "RAN_KAFKA_SASL_PASSWORD"
+    EnvSASLOAuthToken        = "RAN_KAFKA_SASL_OAUTH_TOKEN"
+)
+
+// SecurityFromEnv reads the security settings from the RAN_KAFKA_TLS_* and
+// RAN_KAFKA_SASL_* environment variables. A RAN_KAFKA_SASL_OAUTH_TOKEN is
+// used as a static OAUTHBEARER token.
+func SecurityFromEnv() Security {
+    security := Security{
+        TLS: TLS{
+            Enabled:            envBool(EnvTLSEnabled),
+            CAFile:             os.Getenv(EnvTLSCAFile),
+            CertFile:           os.Getenv(EnvTLSCertFile),
+            KeyFile:            os.Getenv(EnvTLSKeyFile),
+            InsecureSkipVerify: envBool(EnvTLSInsecureSkipVerify),
+            ServerName:         os.Getenv(EnvTLSServerName),
+        },
+        SASL: SASL{
+            Mechanism: os.Getenv(EnvSASLMechanism),
+            Username:  os.Getenv(EnvSASLUsername),
+            Password:  os.Getenv(EnvSASLPassword),
+        },
+    }
+    if token := os.Getenv(EnvSASLOAuthToken); token != "" {
+        security.SASL.TokenProvider = StaticToken(token)
+    }
+    return security
+}
+
+func envBool(name string) bool {
+    value, _ := strconv.ParseBool(os.Getenv(name))
+    return value
+}
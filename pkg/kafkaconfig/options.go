@@ -0,0 +1,132 @@
+package kafkaconfig
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// Option overrides part of the sarama configuration a client builds from
+// its package defaults and its Config. Options are applied after both, in
+// order, and the result is checked with sarama's Config.Validate.
+type Option struct {
+    name  string
+    apply func(*sarama.Config)
+}
+
+// String returns the name the option is reported under.
+func (o Option) String() string {
+    return o.name
+}
+
+// Override returns an option named name that calls fn, for settings without
+// a dedicated option.
+func Override(name string, fn func(*sarama.Config)) Option {
+    return Option{name: name, apply: fn}
+}
+
+// WithClientID sets the client ID sent to the brokers.
+func WithClientID(id string) Option {
+    return Override("client_id="+id, func(c *sarama.Config) { c.ClientID = id })
+}
+
+// WithVersion sets the Kafka protocol version.
+func WithVersion(version sarama.KafkaVersion) Option {
+    return Override("version="+version.String(), func(c *sarama.Config) { c.Version = version })
+}
+
+// WithRequiredAcks sets the acknowledgements a produce request waits for.
+func WithRequiredAcks(acks sarama.RequiredAcks) Option {
+    return Override(fmt.Sprintf("required_acks=%d", acks), func(c *sarama.Config) { c.Producer.RequiredAcks = acks })
+}
+
+// WithCompression sets the compression codec of produced batches.
+func WithCompression(codec sarama.CompressionCodec) Option {
+    return Override("compression="+codec.String(), func(c *sarama.Config) { c.Producer.Compression = codec })
+}
+
+// WithMaxMessageBytes sets the largest message the producer sends.
+func WithMaxMessageBytes(n int) Option {
+    return Override(fmt.Sprintf("max_message_bytes=%d", n), func(c *sarama.Config) { c.Producer.MaxMessageBytes = n })
+}
+
+// WithLinger sets how long the producer waits to fill a batch.
+func WithLinger(d time.Duration) Option {
+    return Override("linger="+d.String(), func(c *sarama.Config) { c.Producer.Flush.Frequency = d })
+}
+
+// WithRetryMax sets how often a failed produce request is retried.
+func WithRetryMax(n int) Option {
+    return Override(fmt.Sprintf("retry_max=%d", n), func(c *sarama.Config) { c.Producer.Retry.Max = n })
+}
+
+// WithFetchSizes sets the minimum, default and maximum bytes fetched per
+// request. Zero values are left unchanged.
+func WithFetchSizes(minBytes, defaultBytes, maxBytes int32) Option {
+    return Override(fmt.Sprintf("fetch_sizes=%d/%d/%d", minBytes, defaultBytes, maxBytes), func(c *sarama.Config) {
+        if minBytes > 0 {
+            c.Consumer.Fetch.Min = minBytes
+        }
+        if defaultBytes > 0 {
+            c.Consumer.Fetch.Default = defaultBytes
+        }
+        if maxBytes > 0 {
+            c.Consumer.Fetch.Max = maxBytes
+        }
+    })
+}
+
+// WithMaxWait sets how long the broker waits for Fetch.Min bytes.
+func WithMaxWait(d time.Duration) Option {
+    return Override("max_wait="+d.String(), func(c *sarama.Config) { c.Consumer.MaxWaitTime = d })
+}
+
+// ApplyOptions applies options to config in order and returns their names.
+func ApplyOptions(config *sarama.Config, options []Option) []string {
+    names := make([]string, 0, len(options))
+    for _, option := range options {
+        option.apply(config)
+        names = append(names, option.name)
+    }
+    return names
+}
+
+// BaseConfig returns a copy of base, or defaults() when base is nil. The
+// copy gets its own metric registry so clients sharing a base do not mix
+// their metrics.
+func BaseConfig(base *sarama.Config, defaults func() *sarama.Config) *sarama.Config {
+    if base == nil {
+        return defaults()
+    }
+    config := *base
+    config.MetricRegistry = gometrics.NewRegistry()
+    return &config
+}
+
+// ValidateOverrides checks config with sarama's Validate, naming the
+// applied overrides in the error since they are the usual culprit.
+func ValidateOverrides(config *sarama.Config, overrides []string) error {
+    err := config.Validate()
+    if err == nil || len(overrides) == 0 {
+        return err
+    }
+    return fmt.Errorf("%w (overrides: %s)", err, strings.Join(overrides, ", "))
+}
+
+// ParseRequiredAcks parses "all", "leader" or "none", or the equivalent
+// -1, 1 or 0.
+func ParseRequiredAcks(s string) (sarama.RequiredAcks, error) {
+    switch strings.ToLower(s) {
+    case "all", "-1":
+        return sarama.WaitForAll, nil
+    case "leader", "1":
+        return sarama.WaitForLocal, nil
+    case "none", "0":
+        return sarama.NoResponse, nil
+    default:
+        return 0, fmt.Errorf("invalid required acks %q, expected all, leader or none", s)
+    }
+}
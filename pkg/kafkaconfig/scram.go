@@ -0,0 +1,39 @@
+package kafkaconfig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/xdg-go/scram"
+)
+
+var (
+    sha256Hash scram.HashGeneratorFcn = sha256.New
+    sha512Hash scram.HashGeneratorFcn = sha512.New
+)
+
+// scramClient implements sarama.SCRAMClient with xdg-go/scram.
+type scramClient struct {
+    *scram.ClientConversation
+    hashGenerator scram.HashGeneratorFcn
+}
+
+// Begin implements sarama.SCRAMClient
+func (c *scramClient) Begin(userName, password, authzID string) error {
+    client, err := c.hashGenerator.NewClient(userName, password, authzID)
+    if err != nil {
+        return err
+    }
+    c.ClientConversation = client.NewConversation()
+    return nil
+}
+
+// Step implements sarama.SCRAMClient
+func (c *scramClient) Step(challenge string) (string, error) {
+    return c.ClientConversation.Step(challenge)
+}
+
+// Done implements sarama.SCRAMClient
+func (c *scramClient) Done() bool {
+    return c.ClientConversation.Done()
+}
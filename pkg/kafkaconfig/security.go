@@ -0,0 +1,161 @@
+// Package kafkaconfig holds the connection settings shared by the producer,
+// consumer, pipeline and admin clients.
+package kafkaconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// SASL mechanisms accepted by SASL.Mechanism.
+const (
+    MechanismPlain       = sarama.SASLTypePlaintext
+    MechanismSCRAMSHA256 = sarama.SASLTypeSCRAMSHA256
+    MechanismSCRAMSHA512 = sarama.SASLTypeSCRAMSHA512
+    MechanismOAuthBearer = sarama.SASLTypeOAuth
+)
+
+// Security configures how clients authenticate to and encrypt traffic with
+// the brokers. The zero value connects in plaintext without authentication.
+type Security struct {
+    TLS  TLS
+    SASL SASL
+}
+
+// TLS configures encryption. It is enabled by Enabled or by setting any of
+// the file options.
+type TLS struct {
+    Enabled bool
+    // CAFile is a PEM bundle of CAs trusted in addition to the system pool.
+    CAFile string
+    // CertFile and KeyFile are the PEM client certificate and key for
+    // mutual TLS.
+    CertFile string
+    KeyFile  string
+    // InsecureSkipVerify disables broker certificate verification.
+    InsecureSkipVerify bool
+    // ServerName overrides the name verified in broker certificates.
+    ServerName string
+}
+
+// SASL configures authentication. An empty Mechanism disables it.
+type SASL struct {
+    // Mechanism is PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER.
+    Mechanism string
+    // Username and Password are used by PLAIN and SCRAM.
+    Username string
+    Password string
+    // TokenProvider supplies OAUTHBEARER tokens; see StaticToken and
+    // TokenProviderFunc.
+    TokenProvider sarama.AccessTokenProvider
+}
+
+// TokenProviderFunc adapts a function to sarama.AccessTokenProvider, e.g. to
+// fetch tokens from an OAuth client credentials flow. It is called on every
+// new broker connection.
+type TokenProviderFunc func() (*sarama.AccessToken, error)
+
+// Token implements sarama.AccessTokenProvider
+func (f TokenProviderFunc) Token() (*sarama.AccessToken, error) {
+    return f()
+}
+
+// StaticToken returns a provider that always supplies token.
+func StaticToken(token string) sarama.AccessTokenProvider {
+    return TokenProviderFunc(func() (*sarama.AccessToken, error) {
+        return &sarama.AccessToken{Token: token}, nil
+    })
+}
+
+func (t TLS) enabled() bool {
+    return t.Enabled || t.CAFile != "" || t.CertFile != "" || t.KeyFile != ""
+}
+
+// Apply validates the settings and configures saramaConfig with them.
+func (s Security) Apply(saramaConfig *sarama.Config) error {
+    if s.TLS.enabled() {
+        tlsConfig, err := s.TLS.config()
+        if err != nil {
+            return err
+        }
+        saramaConfig.Net.TLS.Enable = true
+        saramaConfig.Net.TLS.Config = tlsConfig
+    }
+    return s.SASL.apply(saramaConfig)
+}
+
+func (t TLS) config() (*tls.Config, error) {
+    config := &tls.Config{
+        MinVersion:         tls.VersionTLS12,
+        InsecureSkipVerify: t.InsecureSkipVerify,
+        ServerName:         t.ServerName,
+    }
+
+    if t.CAFile != "" {
+        pem, err := os.ReadFile(t.CAFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+        }
+        pool, err := x509.SystemCertPool()
+        if err != nil {
+            pool = x509.NewCertPool()
+        }
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("no certificates found in TLS CA file %s", t.CAFile)
+        }
+        config.RootCAs = pool
+    }
+
+    if (t.CertFile == "") != (t.KeyFile == "") {
+        return nil, fmt.Errorf("TLS client certificate and key must be set together")
+    }
+    if t.CertFile != "" {
+        cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+        }
+        config.Certificates = []tls.Certificate{cert}
+    }
+    return config, nil
+}
+
+func (s SASL) apply(saramaConfig *sarama.Config) error {
+    mechanism := sarama.SASLMechanism(strings.ToUpper(s.Mechanism))
+    switch mechanism {
+    case "":
+        return nil
+    case MechanismPlain, MechanismSCRAMSHA256, MechanismSCRAMSHA512:
+        if s.Username == "" {
+            return fmt.Errorf("SASL %s requires a username", mechanism)
+        }
+        saramaConfig.Net.SASL.User = s.Username
+        saramaConfig.Net.SASL.Password = s.Password
+    case MechanismOAuthBearer:
+        if s.TokenProvider == nil {
+            return fmt.Errorf("SASL %s requires a token provider", mechanism)
+        }
+        saramaConfig.Net.SASL.TokenProvider = s.TokenProvider
+    default:
+        return fmt.Errorf("unsupported SASL mechanism %q", s.Mechanism)
+    }
+
+    saramaConfig.Net.SASL.Enable = true
+    saramaConfig.Net.SASL.Handshake = true
+    saramaConfig.Net.SASL.Mechanism = mechanism
+    switch mechanism {
+    case MechanismSCRAMSHA256:
+        saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+            return &scramClient{hashGenerator: sha256Hash}
+        }
+    case MechanismSCRAMSHA512:
+        saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+            return &scramClient{hashGenerator: sha512Hash}
+        }
+    }
+    return nil
+}
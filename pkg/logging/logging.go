@@ -0,0 +1,71 @@
+// Package logging holds the log/slog helpers shared by the producer and the
+// consumer.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxPayload is the number of payload bytes logged when payload
+// logging is enabled without a limit.
+const DefaultMaxPayload = 256
+
+// Payload controls whether message payloads are logged. Payloads are off by
+// default as they may contain personal data.
+type Payload struct {
+    Enabled bool
+    // MaxBytes truncates logged payloads. Defaults to DefaultMaxPayload.
+    MaxBytes int
+}
+
+// Attr returns the "payload" attribute for value, or an empty attribute,
+// which slog drops, when payload logging is disabled.
+func (p Payload) Attr(value []byte) slog.Attr {
+    if !p.Enabled {
+        return slog.Attr{}
+    }
+    max := p.MaxBytes
+    if max <= 0 {
+        max = DefaultMaxPayload
+    }
+    if len(value) <= max {
+        return slog.String("payload", string(value))
+    }
+    // Do not cut a UTF-8 sequence in half.
+    cut := max
+    for cut > 0 && !utf8.RuneStart(value[cut]) {
+        cut--
+    }
+    return slog.String("payload", fmt.Sprintf("%s... (%d bytes)", value[:cut], len(value)))
+}
+
+// OrDefault returns logger, or slog.Default() when it is nil.
+func OrDefault(logger *slog.Logger) *slog.Logger {
+    if logger == nil {
+        return slog.Default()
+    }
+    return logger
+}
+
+// New builds a logger writing to w in the given format, "text" or "json",
+// at the given level: "debug", "info", "warn" or "error".
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+    var lvl slog.Level
+    if err := lvl.UnmarshalText([]byte(level)); err != nil {
+        return nil, fmt.Errorf("invalid log level %q", level)
+    }
+    options := &slog.HandlerOptions{Level: lvl}
+
+    switch strings.ToLower(format) {
+    case "text":
+        return slog.New(slog.NewTextHandler(w, options)), nil
+    case "json":
+        return slog.New(slog.NewJSONHandler(w, options)), nil
+    default:
+        return nil, fmt.Errorf("invalid log format %q, expected text or json", format)
+    }
+}
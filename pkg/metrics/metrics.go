@@ -0,0 +1,211 @@
+// Package metrics records producer and consumer metrics with Prometheus and
+// serves them over HTTP. A nil *Metrics is valid and records nothing, so
+// clients can call it unconditionally.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultNamespace prefixes every metric name when New is given none.
+const DefaultNamespace = "ran_kafka"
+
+// Metrics holds the collectors shared by the producers and consumers of a
+// process.
+type Metrics struct {
+    namespace string
+    registry  *prometheus.Registry
+
+    produced       *prometheus.CounterVec
+    producedBytes  *prometheus.CounterVec
+    produceErrors  *prometheus.CounterVec
+    produceLatency *prometheus.HistogramVec
+    consumed       *prometheus.CounterVec
+    consumeErrors  *prometheus.CounterVec
+    processLatency *prometheus.HistogramVec
+    batchSize      *prometheus.HistogramVec
+    sinkWrites     *prometheus.CounterVec
+    sinkErrors     *prometheus.CounterVec
+    sinkLatency    *prometheus.HistogramVec
+    lag            *prometheus.GaugeVec
+
+    // clients counts the sarama registries bridged per client name, so
+    // each gets a unique client label.
+    mu      sync.Mutex
+    clients map[string]int
+}
+
+// New creates the collectors in a dedicated registry, together with the Go
+// runtime and process collectors.
+func New(namespace string) *Metrics {
+    if namespace == "" {
+        namespace = DefaultNamespace
+    }
+    topic := []string{"topic"}
+    partition := []string{"topic", "partition"}
+    sink := []string{"sink"}
+
+    m := &Metrics{
+        namespace: namespace,
+        registry:  prometheus.NewRegistry(),
+        produced: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "produced_messages_total", Help: "Messages acknowledged by the broker.",
+        }, topic),
+        producedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "produced_bytes_total", Help: "Value bytes of acknowledged messages.",
+        }, topic),
+        produceErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "produce_errors_total", Help: "Messages that failed to be produced.",
+        }, topic),
+        produceLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Namespace: namespace, Name: "produce_latency_seconds", Help: "Time from send to broker acknowledgement.",
+            Buckets: prometheus.DefBuckets,
+        }, topic),
+        consumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "consumed_messages_total", Help: "Messages processed by the consumer handler.",
+        }, topic),
+        consumeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "consume_errors_total", Help: "Messages the consumer handler failed to process.",
+        }, topic),
+        processLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Namespace: namespace, Name: "processing_latency_seconds", Help: "Time spent in the consumer handler per message or batch.",
+            Buckets: prometheus.DefBuckets,
+        }, topic),
+        batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Namespace: namespace, Name: "batch_size_messages", Help: "Messages per batch handed to a batch handler.",
+            Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+        }, topic),
+        sinkWrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "sink_written_messages_total", Help: "Messages written to a sink.",
+        }, sink),
+        sinkErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: namespace, Name: "sink_errors_total", Help: "Failed sink writes.",
+        }, sink),
+        sinkLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Namespace: namespace, Name: "sink_write_latency_seconds", Help: "Duration of sink writes.",
+            Buckets: prometheus.DefBuckets,
+        }, sink),
+        lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Namespace: namespace, Name: "consumer_lag_messages", Help: "Messages between the last consumed offset and the high water mark.",
+        }, partition),
+        clients: map[string]int{},
+    }
+
+    m.registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+        m.produced, m.producedBytes, m.produceErrors, m.produceLatency,
+        m.consumed, m.consumeErrors, m.processLatency, m.batchSize,
+        m.sinkWrites, m.sinkErrors, m.sinkLatency, m.lag,
+    )
+    return m
+}
+
+// Registry returns the registry holding every collector, e.g. to register
+// application metrics next to them.
+func (m *Metrics) Registry() *prometheus.Registry {
+    return m.registry
+}
+
+// Handler returns the HTTP handler exposing the registry.
+func (m *Metrics) Handler() http.Handler {
+    return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve exposes the metrics on addr under /metrics until ctx is done.
+func (m *Metrics) Serve(ctx context.Context, addr string) error {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", m.Handler())
+    server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+    stop := context.AfterFunc(ctx, func() {
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        server.Shutdown(shutdownCtx)
+    })
+    defer stop()
+
+    if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    return nil
+}
+
+// ObserveProduce records a message sent to topic with a value of the given
+// size.
+func (m *Metrics) ObserveProduce(topic string, bytes int, latency time.Duration, err error) {
+    if m == nil {
+        return
+    }
+    if err != nil {
+        m.produceErrors.WithLabelValues(topic).Inc()
+        return
+    }
+    m.produced.WithLabelValues(topic).Inc()
+    m.producedBytes.WithLabelValues(topic).Add(float64(bytes))
+    m.produceLatency.WithLabelValues(topic).Observe(latency.Seconds())
+}
+
+// ObserveProcess records a handler call over n messages of topic.
+func (m *Metrics) ObserveProcess(topic string, n int, latency time.Duration, err error) {
+    if m == nil {
+        return
+    }
+    m.processLatency.WithLabelValues(topic).Observe(latency.Seconds())
+    if err != nil {
+        m.consumeErrors.WithLabelValues(topic).Add(float64(n))
+        return
+    }
+    m.consumed.WithLabelValues(topic).Add(float64(n))
+}
+
+// ObserveBatch records the size of a batch handed to a batch handler.
+func (m *Metrics) ObserveBatch(topic string, n int) {
+    if m == nil {
+        return
+    }
+    m.batchSize.WithLabelValues(topic).Observe(float64(n))
+}
+
+// ObserveSinkWrite records a write of n messages to a sink such as "mongo".
+func (m *Metrics) ObserveSinkWrite(sink string, n int, latency time.Duration, err error) {
+    if m == nil {
+        return
+    }
+    m.sinkLatency.WithLabelValues(sink).Observe(latency.Seconds())
+    if err != nil {
+        m.sinkErrors.WithLabelValues(sink).Inc()
+        return
+    }
+    m.sinkWrites.WithLabelValues(sink).Add(float64(n))
+}
+
+// SetLag records the lag of a partition after consuming offset, given the
+// partition's high water mark.
+func (m *Metrics) SetLag(topic string, partition int32, offset, highWaterMark int64) {
+    if m == nil {
+        return
+    }
+    lag := highWaterMark - offset - 1
+    if lag < 0 {
+        lag = 0
+    }
+    m.lag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
+
+// DeleteLag drops the lag of a partition that is no longer claimed.
+func (m *Metrics) DeleteLag(topic string, partition int32) {
+    if m == nil {
+        return
+    }
+    m.lag.DeleteLabelValues(topic, strconv.Itoa(int(partition)))
+}
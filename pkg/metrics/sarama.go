@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// saramaQuantiles are exported for sarama histograms and timers.
+var saramaQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// saramaCollector exposes a sarama go-metrics registry, read on every scrape.
+// Metric names are prefixed with "<namespace>_sarama_" and sanitised, and
+// every sample carries the client label.
+type saramaCollector struct {
+    namespace string
+    client    string
+    registry  gometrics.Registry
+}
+
+// RegisterSarama bridges the go-metrics registry of a sarama client, i.e.
+// its sarama.Config.MetricRegistry. Registries bridged under the same client
+// name get a numeric suffix. The returned func unregisters the bridge.
+func (m *Metrics) RegisterSarama(client string, registry gometrics.Registry) func() {
+    if m == nil || registry == nil {
+        return func() {}
+    }
+
+    m.mu.Lock()
+    m.clients[client]++
+    if n := m.clients[client]; n > 1 {
+        client = fmt.Sprintf("%s-%d", client, n)
+    }
+    m.mu.Unlock()
+
+    collector := &saramaCollector{namespace: m.namespace, client: client, registry: registry}
+    m.registry.MustRegister(collector)
+    return func() { m.registry.Unregister(collector) }
+}
+
+// Describe implements prometheus.Collector. It sends nothing, which makes
+// the collector unchecked, as the set of sarama metrics grows at runtime.
+func (c *saramaCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (c *saramaCollector) Collect(ch chan<- prometheus.Metric) {
+    c.registry.Each(func(name string, metric interface{}) {
+        name = c.name(name)
+        switch metric := metric.(type) {
+        case gometrics.Counter:
+            c.send(ch, name+"_total", prometheus.CounterValue, float64(metric.Count()))
+        case gometrics.Gauge:
+            c.send(ch, name, prometheus.GaugeValue, float64(metric.Value()))
+        case gometrics.GaugeFloat64:
+            c.send(ch, name, prometheus.GaugeValue, metric.Value())
+        case gometrics.Meter:
+            snapshot := metric.Snapshot()
+            c.send(ch, name+"_total", prometheus.CounterValue, float64(snapshot.Count()))
+            c.send(ch, name+"_rate1m", prometheus.GaugeValue, snapshot.Rate1())
+        case gometrics.Histogram:
+            snapshot := metric.Snapshot()
+            c.summary(ch, name, uint64(snapshot.Count()), float64(snapshot.Sum()), snapshot.Percentiles(saramaQuantiles))
+        case gometrics.Timer:
+            snapshot := metric.Snapshot()
+            c.summary(ch, name, uint64(snapshot.Count()), float64(snapshot.Sum()), snapshot.Percentiles(saramaQuantiles))
+        }
+    })
+}
+
+func (c *saramaCollector) name(name string) string {
+    sanitised := strings.Map(func(r rune) rune {
+        if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+            return r
+        }
+        return '_'
+    }, name)
+    return c.namespace + "_sarama_" + sanitised
+}
+
+func (c *saramaCollector) send(ch chan<- prometheus.Metric, name string, kind prometheus.ValueType, value float64) {
+    desc := prometheus.NewDesc(name, "sarama metric", nil, prometheus.Labels{"client": c.client})
+    ch <- prometheus.MustNewConstMetric(desc, kind, value)
+}
+
+func (c *saramaCollector) summary(ch chan<- prometheus.Metric, name string, count uint64, sum float64, values []float64) {
+    quantiles := make(map[float64]float64, len(values))
+    for i, q := range saramaQuantiles {
+        quantiles[q] = values[i]
+    }
+    desc := prometheus.NewDesc(name, "sarama metric", nil, prometheus.Labels{"client": c.client})
+    ch <- prometheus.MustNewConstSummary(desc, count, sum, quantiles)
+}
@@ -0,0 +1,334 @@
+// Package pipeline implements a transactional consume-transform-produce
+// loop. The input messages of a claim are transformed and their outputs are
+// produced inside a Kafka transaction that also commits the consumer
+// offset, so outputs and the offset commit either both happen or neither
+// does.
+//
+// Every claimed partition gets its own transactional producer, with the ID
+// "<TransactionalID>-<topic>-<partition>". When a partition moves to
+// another instance, the new owner's producer fences the old one, so an
+// instance that has not yet noticed the rebalance can no longer commit
+// outputs or offsets for it. Together with read_committed consumers of the
+// outputs this gives exactly-once processing, as long as every instance of
+// the pipeline uses the same TransactionalID prefix.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/metrics"
+	"github.com/radheem/ran-kafka-client-go/pkg/producer"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultBatchSize is used when Config.BatchSize is not set.
+const DefaultBatchSize = 100
+
+type Config struct {
+    Brokers       []string
+    Security      kafkaconfig.Security
+    InputTopics   []string
+    ConsumerGroup string
+    // TransactionalID prefixes the IDs of the per-partition producers. It
+    // must be the same on every instance of the pipeline and unique among
+    // pipelines.
+    TransactionalID string
+    Transform       TransformFunc
+    // BatchSize caps the messages of a partition committed in one
+    // transaction. A transaction takes the messages already fetched,
+    // without waiting for more. Defaults to DefaultBatchSize.
+    BatchSize int
+
+    // SaramaOptions override individual sarama settings of the consumer
+    // group and the producers after everything else.
+    SaramaOptions []kafkaconfig.Option
+
+    // Logger receives the pipeline's logs. Defaults to slog.Default().
+    Logger *slog.Logger
+    // Metrics, if set, records consume and produce counts, transaction
+    // sizes and per-partition lag, along with sarama's own metrics.
+    Metrics *metrics.Metrics
+    // TracerProvider creates a consume span around every transform,
+    // continuing the trace found in the message headers, with the send
+    // spans of its outputs as children. Defaults to the global provider.
+    TracerProvider trace.TracerProvider
+    // Propagator extracts and injects the trace context in message
+    // headers. Defaults to W3C trace context (traceparent and tracestate).
+    Propagator propagation.TextMapPropagator
+}
+
+// Record is a message produced by a transform.
+type Record struct {
+    Topic   string
+    Key     string
+    Value   []byte
+    Headers map[string]string
+}
+
+// TransformFunc turns one input message into zero or more output records.
+// Returning an error aborts the transaction and the message is redelivered.
+type TransformFunc func(ctx context.Context, msg *sarama.ConsumerMessage) ([]Record, error)
+
+// errRevoked aborts a transaction whose partition was revoked before it
+// could be committed.
+var errRevoked = errors.New("partition revoked")
+
+type Pipeline struct {
+    config     Config
+    logger     *slog.Logger
+    metrics    *metrics.Metrics
+    unregister func()
+    tracer     trace.Tracer
+    propagator propagation.TextMapPropagator
+    client     sarama.ConsumerGroup
+}
+
+func NewPipeline(config Config) (*Pipeline, error) {
+    if config.Transform == nil {
+        return nil, errors.New("pipeline transform is required")
+    }
+    if config.TransactionalID == "" {
+        return nil, errors.New("pipeline transactional ID is required")
+    }
+    if config.BatchSize < 0 {
+        return nil, errors.New("pipeline batch size must not be negative")
+    }
+    if config.BatchSize == 0 {
+        config.BatchSize = DefaultBatchSize
+    }
+
+    // Offsets are committed through the transaction, and only committed
+    // output is read.
+    saramaConfig := sarama.NewConfig()
+    saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRoundRobin()
+    saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+    saramaConfig.Consumer.Offsets.AutoCommit.Enable = false
+    saramaConfig.Consumer.IsolationLevel = sarama.ReadCommitted
+    saramaConfig.Consumer.Group.Session.Timeout = 10 * time.Second
+    saramaConfig.Consumer.Group.Heartbeat.Interval = 3 * time.Second
+    if err := config.Security.Apply(saramaConfig); err != nil {
+        return nil, err
+    }
+    overrides := kafkaconfig.ApplyOptions(saramaConfig, config.SaramaOptions)
+    if err := kafkaconfig.ValidateOverrides(saramaConfig, overrides); err != nil {
+        return nil, fmt.Errorf("invalid pipeline configuration: %w", err)
+    }
+    logger := logging.OrDefault(config.Logger)
+    if len(overrides) > 0 {
+        logger.Info("Applied sarama overrides", slog.Any("overrides", overrides))
+    }
+
+    client, err := sarama.NewConsumerGroup(config.Brokers, config.ConsumerGroup, saramaConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create consumer group: %w", err)
+    }
+
+    return &Pipeline{
+        config:     config,
+        logger:     logger,
+        metrics:    config.Metrics,
+        unregister: config.Metrics.RegisterSarama(config.ConsumerGroup, saramaConfig.MetricRegistry),
+        tracer:     tracing.Tracer(config.TracerProvider),
+        propagator: tracing.Propagator(config.Propagator),
+        client:     client,
+    }, nil
+}
+
+// Run consumes the input topics until ctx is done or the group fails.
+func (p *Pipeline) Run(ctx context.Context) error {
+    p.logger.Info("Starting pipeline", slog.Any("topics", p.config.InputTopics))
+    for ctx.Err() == nil {
+        if err := p.client.Consume(ctx, p.config.InputTopics, p); err != nil {
+            if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+                return nil
+            }
+            return fmt.Errorf("pipeline consume failed: %w", err)
+        }
+    }
+    return nil
+}
+
+func (p *Pipeline) Close() error {
+    defer p.unregister()
+    return p.client.Close()
+}
+
+// Setup implements sarama.ConsumerGroupHandler
+func (p *Pipeline) Setup(sarama.ConsumerGroupSession) error {
+    return nil
+}
+
+// Cleanup implements sarama.ConsumerGroupHandler
+func (p *Pipeline) Cleanup(session sarama.ConsumerGroupSession) error {
+    for topic, partitions := range session.Claims() {
+        for _, partition := range partitions {
+            p.metrics.DeleteLag(topic, partition)
+        }
+    }
+    return nil
+}
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler
+func (p *Pipeline) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+    // Creating the producer fences the previous owner of the partition.
+    prod, err := p.newProducer(claim.Topic(), claim.Partition())
+    if err != nil {
+        return err
+    }
+    defer prod.Close()
+
+    batch := make([]*sarama.ConsumerMessage, 0, p.config.BatchSize)
+    for {
+        select {
+        case message := <-claim.Messages():
+            if message == nil {
+                return nil
+            }
+            batch = p.gather(claim, append(batch[:0], message))
+            last := batch[len(batch)-1]
+            p.metrics.SetLag(last.Topic, last.Partition, last.Offset, claim.HighWaterMarkOffset())
+
+            if err := p.process(session, prod, batch); err != nil {
+                if errors.Is(err, errRevoked) {
+                    p.logger.Info("Partition revoked, transaction aborted",
+                        slog.String("topic", claim.Topic()),
+                        slog.Int("partition", int(claim.Partition())),
+                        slog.Int64("first_offset", batch[0].Offset))
+                    return nil
+                }
+                p.logger.Error("Failed to process messages",
+                    slog.String("topic", claim.Topic()),
+                    slog.Int("partition", int(claim.Partition())),
+                    slog.Int64("first_offset", batch[0].Offset),
+                    slog.Int64("last_offset", last.Offset),
+                    slog.Any("error", err))
+                // Rewind to the first message of the transaction; the
+                // session restarts from the last committed offset anyway.
+                session.ResetOffset(claim.Topic(), claim.Partition(), batch[0].Offset, "")
+                return err
+            }
+
+        case <-session.Context().Done():
+            return nil
+        }
+    }
+}
+
+// newProducer creates the transactional producer of a claimed partition.
+func (p *Pipeline) newProducer(topic string, partition int32) (*producer.Producer, error) {
+    prod, err := producer.NewProducer(producer.Config{
+        Brokers:         p.config.Brokers,
+        Security:        p.config.Security,
+        TransactionalID: fmt.Sprintf("%s-%s-%d", p.config.TransactionalID, topic, partition),
+        SaramaOptions:   p.config.SaramaOptions,
+        Logger:          p.config.Logger,
+        Metrics:         p.config.Metrics,
+        TracerProvider:  p.config.TracerProvider,
+        Propagator:      p.config.Propagator,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to create transactional producer for %s[%d]: %w", topic, partition, err)
+    }
+    return prod, nil
+}
+
+// gather adds the messages of claim that are already fetched to batch, up
+// to BatchSize, without waiting for more.
+func (p *Pipeline) gather(claim sarama.ConsumerGroupClaim, batch []*sarama.ConsumerMessage) []*sarama.ConsumerMessage {
+    for len(batch) < p.config.BatchSize {
+        select {
+        case message := <-claim.Messages():
+            if message == nil {
+                return batch
+            }
+            batch = append(batch, message)
+        default:
+            return batch
+        }
+    }
+    return batch
+}
+
+// process runs the transaction of batch and records its metrics.
+func (p *Pipeline) process(session sarama.ConsumerGroupSession, prod *producer.Producer, batch []*sarama.ConsumerMessage) error {
+    topic := batch[0].Topic
+    start := time.Now()
+    err := p.transact(session, prod, batch)
+    p.metrics.ObserveBatch(topic, len(batch))
+    p.metrics.ObserveProcess(topic, len(batch), time.Since(start), err)
+    return err
+}
+
+// transact transforms batch and commits its outputs together with the
+// offset after its last message. The transaction is aborted if the session
+// ended meanwhile, since the partition may already belong to another
+// member; should the revocation come later still, the new owner's producer
+// has fenced prod and the commit fails.
+func (p *Pipeline) transact(session sarama.ConsumerGroupSession, prod *producer.Producer, batch []*sarama.ConsumerMessage) error {
+    if err := prod.BeginTxn(); err != nil {
+        return err
+    }
+
+    for _, msg := range batch {
+        if err := p.produce(session.Context(), prod, msg); err != nil {
+            return p.abort(prod, err)
+        }
+    }
+
+    if err := prod.AddMessageToTxn(batch[len(batch)-1], p.config.ConsumerGroup); err != nil {
+        return p.abort(prod, err)
+    }
+
+    if err := session.Context().Err(); err != nil {
+        return p.abort(prod, fmt.Errorf("%w: %w", errRevoked, err))
+    }
+    if err := prod.CommitTxn(); err != nil {
+        return p.abort(prod, err)
+    }
+    return nil
+}
+
+// produce transforms msg and sends its outputs in a consume span that
+// continues the trace propagated in the message headers.
+func (p *Pipeline) produce(ctx context.Context, prod *producer.Producer, msg *sarama.ConsumerMessage) (err error) {
+    ctx = p.propagator.Extract(ctx, tracing.ConsumerHeaders{Msg: msg})
+    ctx, span := p.tracer.Start(ctx, "process "+msg.Topic,
+        trace.WithSpanKind(trace.SpanKindConsumer),
+        trace.WithAttributes(tracing.MessageAttributes(msg.Topic, msg.Key)...),
+        trace.WithAttributes(tracing.OffsetAttributes(msg.Partition, msg.Offset)...))
+    defer func() { tracing.EndSpan(span, err) }()
+
+    records, err := p.config.Transform(ctx, msg)
+    if err != nil {
+        return fmt.Errorf("transform of %s[%d]@%d failed: %w", msg.Topic, msg.Partition, msg.Offset, err)
+    }
+
+    for _, record := range records {
+        out := producer.Record{Key: record.Key, Value: record.Value, Headers: record.Headers}
+        if _, err := prod.SendRecordContext(ctx, record.Topic, out); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// abort aborts the current transaction unless the producer is in a fatal
+// state, in which case it can no longer be used.
+func (p *Pipeline) abort(prod *producer.Producer, cause error) error {
+    if prod.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+        return fmt.Errorf("transactional producer in fatal state: %w", cause)
+    }
+    if err := prod.AbortTxn(); err != nil {
+        return fmt.Errorf("%w (abort failed: %v)", cause, err)
+    }
+    return cause
+}
@@ -0,0 +1,154 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+)
+
+func newAsyncProducer(config Config, saramaConfig *sarama.Config) (*Producer, error) {
+    client, err := sarama.NewAsyncProducer(config.Brokers, saramaConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create async producer: %w", err)
+    }
+
+    p := &Producer{
+        config:      config,
+        logger:      logging.OrDefault(config.Logger),
+        metrics:     config.Metrics,
+        unregister:  config.Metrics.RegisterSarama("producer", saramaConfig.MetricRegistry),
+        tracer:      tracing.Tracer(config.TracerProvider),
+        propagator:  tracing.Propagator(config.Propagator),
+        asyncClient: client,
+        done:        make(chan struct{}),
+    }
+    if config.ReturnDeliveries {
+        p.successes = make(chan *Delivery, saramaConfig.ChannelBufferSize)
+        p.errors = make(chan *Delivery, saramaConfig.ChannelBufferSize)
+    }
+
+    go p.dispatchDeliveries()
+    return p, nil
+}
+
+// SendAsync queues a message on an async producer and returns immediately.
+// The callback, if not nil, is invoked from the producer's delivery
+// goroutine once the broker acknowledges the message or sending fails.
+func (p *Producer) SendAsync(topic string, msg Message, callback DeliveryCallback) error {
+    producerMsg, err := p.newProducerMessage(context.Background(), topic, msg)
+    if err != nil {
+        return err
+    }
+    return p.enqueue(producerMsg, callback)
+}
+
+// SendRawAsync is the raw byte variant of SendAsync.
+func (p *Producer) SendRawAsync(topic, key string, value []byte, headers map[string]string, callback DeliveryCallback) error {
+    return p.SendRecordAsync(topic, Record{Key: key, Value: value, Headers: headers}, callback)
+}
+
+// SendRecordAsync is the Record variant of SendAsync.
+func (p *Producer) SendRecordAsync(topic string, record Record, callback DeliveryCallback) error {
+    return p.enqueue(newRecordMessage(topic, record), callback)
+}
+
+func (p *Producer) enqueue(producerMsg *sarama.ProducerMessage, callback DeliveryCallback) error {
+    if p.asyncClient == nil {
+        return fmt.Errorf("SendAsync on sync producer: %w", ErrUnsupportedMode)
+    }
+
+    producerMsg.Metadata = &pending{callback: callback, sent: time.Now()}
+    p.inflight.add()
+    p.asyncClient.Input() <- producerMsg
+    return nil
+}
+
+// Successes returns the channel of acknowledged deliveries. It is nil unless
+// Config.Async and Config.ReturnDeliveries are set.
+func (p *Producer) Successes() <-chan *Delivery {
+    return p.successes
+}
+
+// Errors returns the channel of failed deliveries. It is nil unless
+// Config.Async and Config.ReturnDeliveries are set.
+func (p *Producer) Errors() <-chan *Delivery {
+    return p.errors
+}
+
+// Flush blocks until every message queued with SendAsync has been
+// acknowledged or has failed, or until ctx is done.
+func (p *Producer) Flush(ctx context.Context) error {
+    if p.asyncClient == nil {
+        return nil
+    }
+    return p.inflight.wait(ctx)
+}
+
+// dispatchDeliveries drains the sarama success and error channels until the
+// async producer is closed.
+func (p *Producer) dispatchDeliveries() {
+    defer close(p.done)
+    if p.successes != nil {
+        defer close(p.successes)
+        defer close(p.errors)
+    }
+
+    successes := p.asyncClient.Successes()
+    errs := p.asyncClient.Errors()
+    for successes != nil || errs != nil {
+        select {
+        case msg, ok := <-successes:
+            if !ok {
+                successes = nil
+                continue
+            }
+            p.logSent(msg, msg.Partition, msg.Offset)
+            p.deliver(msg, nil, p.successes)
+
+        case perr, ok := <-errs:
+            if !ok {
+                errs = nil
+                continue
+            }
+            p.logger.Error("Failed to deliver message",
+                slog.String("topic", perr.Msg.Topic),
+                slog.String("key", encoderString(perr.Msg.Key)),
+                slog.Any("error", perr.Err))
+            p.deliver(perr.Msg, perr.Err, p.errors)
+        }
+    }
+}
+
+// pending is the metadata of a message queued on the async producer.
+type pending struct {
+    callback DeliveryCallback
+    sent     time.Time
+}
+
+func (p *Producer) deliver(msg *sarama.ProducerMessage, err error, out chan *Delivery) {
+    defer p.inflight.done()
+
+    meta, _ := msg.Metadata.(*pending)
+    if meta != nil {
+        p.metrics.ObserveProduce(msg.Topic, msg.Value.Length(), time.Since(meta.sent), err)
+    }
+
+    delivery := &Delivery{
+        Topic:     msg.Topic,
+        Partition: msg.Partition,
+        Offset:    msg.Offset,
+        Key:       encoderString(msg.Key),
+        Err:       err,
+    }
+    if meta != nil && meta.callback != nil {
+        meta.callback(delivery)
+    }
+    if out != nil {
+        out <- delivery
+    }
+}
@@ -1,21 +1,98 @@
 package producer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/radheem/ran-kafka-client-go/pkg/kafkaconfig"
+	"github.com/radheem/ran-kafka-client-go/pkg/logging"
+	"github.com/radheem/ran-kafka-client-go/pkg/metrics"
+	"github.com/radheem/ran-kafka-client-go/pkg/serde"
+	"github.com/radheem/ran-kafka-client-go/pkg/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
     Brokers []string
+    // Security configures TLS and SASL.
+    Security kafkaconfig.Security
+
+    // Async selects a sarama.AsyncProducer. Messages are then sent with
+    // SendAsync and batched according to the flush frequency.
+    Async bool
+    // ReturnDeliveries makes an async producer publish every delivery report
+    // on the Successes and Errors channels, which must then be drained.
+    ReturnDeliveries bool
+
+    // TransactionalID makes the producer idempotent and transactional.
+    // Messages must then be sent between BeginTxn and CommitTxn/AbortTxn.
+    // It must be unique and stable per producer instance.
+    TransactionalID string
+
+    // ManualPartitioning sends every message to Record.Partition instead of
+    // hashing its key.
+    ManualPartitioning bool
+
+    // ValueSerializer encodes Message values, e.g. as Avro with a schema
+    // registry. Defaults to JSON. Records are always sent as given.
+    ValueSerializer serde.Serializer
+
+    // Sarama, if set, replaces DefaultSaramaConfig as the base the producer
+    // applies its own settings to. It is copied, not modified.
+    Sarama *sarama.Config
+    // SaramaOptions override individual sarama settings, such as acks,
+    // compression or linger, after everything else. The applied overrides
+    // are logged when the producer is created.
+    SaramaOptions []kafkaconfig.Option
+
+    // Logger receives the producer's logs. Defaults to slog.Default().
+    Logger *slog.Logger
+    // LogPayloads adds message values, truncated to MaxLogPayload bytes, to
+    // the debug log of every sent message.
+    LogPayloads   bool
+    MaxLogPayload int
+
+    // Metrics, if set, records produce counts, bytes, latencies and errors
+    // per topic, along with sarama's own metrics.
+    Metrics *metrics.Metrics
+
+    // TracerProvider creates the send spans of the *Context methods.
+    // Defaults to the global provider.
+    TracerProvider trace.TracerProvider
+    // Propagator injects the trace context into message headers. Defaults
+    // to W3C trace context (traceparent and tracestate).
+    Propagator propagation.TextMapPropagator
 }
 
 type Producer struct {
-    config Config
-    client sarama.SyncProducer
+    config      Config
+    logger      *slog.Logger
+    metrics     *metrics.Metrics
+    unregister  func()
+    tracer      trace.Tracer
+    propagator  propagation.TextMapPropagator
+    client      sarama.SyncProducer
+    asyncClient sarama.AsyncProducer
+    inflight    inflight
+    successes   chan *Delivery
+    errors      chan *Delivery
+    done        chan struct{}
+}
+
+// Record is a raw message with an explicit partition, which is only honoured
+// with Config.ManualPartitioning.
+type Record struct {
+    Key       string
+    Value     []byte
+    Headers   map[string]string
+    Partition int32
 }
 
 type Message struct {
@@ -24,14 +101,48 @@ type Message struct {
     Headers map[string]string `json:"headers,omitempty"`
 }
 
-func NewProducer(config Config) (*Producer, error) {
-    // Setup Sarama configuration
+// Delivery reports the outcome of a message sent with SendAsync.
+type Delivery struct {
+    Topic     string
+    Partition int32
+    Offset    int64
+    Key       string
+    Err       error
+}
+
+// DeliveryCallback is invoked once a message sent with SendAsync has been
+// acknowledged by the broker or has failed.
+type DeliveryCallback func(*Delivery)
+
+// ErrUnsupportedMode is returned by the async API when the producer was created
+// without Config.Async, and vice versa.
+var ErrUnsupportedMode = errors.New("operation not supported by this producer mode")
+
+// DefaultSaramaConfig returns the sarama configuration producers start
+// from: acks from all in-sync replicas, three retries, Snappy compression
+// and a 500ms linger.
+func DefaultSaramaConfig() *sarama.Config {
     saramaConfig := sarama.NewConfig()
     saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
     saramaConfig.Producer.Retry.Max = 3
-    saramaConfig.Producer.Return.Successes = true
     saramaConfig.Producer.Compression = sarama.CompressionSnappy
     saramaConfig.Producer.Flush.Frequency = 500 * time.Millisecond
+    return saramaConfig
+}
+
+func NewProducer(config Config) (*Producer, error) {
+    saramaConfig, overrides, err := newSaramaConfig(config)
+    if err != nil {
+        return nil, err
+    }
+    logger := logging.OrDefault(config.Logger)
+    if len(overrides) > 0 {
+        logger.Info("Applied sarama overrides", slog.Any("overrides", overrides))
+    }
+
+    if config.Async {
+        return newAsyncProducer(config, saramaConfig)
+    }
 
     client, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
     if err != nil {
@@ -39,45 +150,184 @@ func NewProducer(config Config) (*Producer, error) {
     }
 
     return &Producer{
-        config: config,
-        client: client,
+        config:     config,
+        logger:     logger,
+        metrics:    config.Metrics,
+        unregister: config.Metrics.RegisterSarama("producer", saramaConfig.MetricRegistry),
+        tracer:     tracing.Tracer(config.TracerProvider),
+        propagator: tracing.Propagator(config.Propagator),
+        client:     client,
     }, nil
 }
 
+// Validate checks config the way NewProducer does, without connecting.
+func (config Config) Validate() error {
+    _, _, err := newSaramaConfig(config)
+    return err
+}
+
+// newSaramaConfig layers the producer settings of config and its sarama
+// overrides over the base configuration, and validates the result. It
+// returns the names of the applied overrides.
+func newSaramaConfig(config Config) (*sarama.Config, []string, error) {
+    saramaConfig := kafkaconfig.BaseConfig(config.Sarama, DefaultSaramaConfig)
+
+    if config.ManualPartitioning {
+        saramaConfig.Producer.Partitioner = sarama.NewManualPartitioner
+    }
+
+    if config.TransactionalID != "" {
+        saramaConfig.Producer.Idempotent = true
+        saramaConfig.Producer.Transaction.ID = config.TransactionalID
+        saramaConfig.Net.MaxOpenRequests = 1
+    }
+
+    if err := config.Security.Apply(saramaConfig); err != nil {
+        return nil, nil, err
+    }
+
+    overrides := kafkaconfig.ApplyOptions(saramaConfig, config.SaramaOptions)
+    // Both producer modes rely on delivery reports.
+    saramaConfig.Producer.Return.Successes = true
+    saramaConfig.Producer.Return.Errors = true
+
+    if err := kafkaconfig.ValidateOverrides(saramaConfig, overrides); err != nil {
+        return nil, nil, fmt.Errorf("invalid producer configuration: %w", err)
+    }
+    return saramaConfig, overrides, nil
+}
+
+// send sends a message with the sync producer inside a send span whose
+// context is injected into the message headers, and records its metrics.
+func (p *Producer) send(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+    key := []byte(encoderString(msg.Key))
+    ctx, span := p.tracer.Start(ctx, "send "+msg.Topic,
+        trace.WithSpanKind(trace.SpanKindProducer),
+        trace.WithAttributes(tracing.MessageAttributes(msg.Topic, key)...))
+    defer func() { tracing.EndSpan(span, err) }()
+    p.propagator.Inject(ctx, tracing.ProducerHeaders{Msg: msg})
+
+    start := time.Now()
+    partition, offset, err = p.client.SendMessage(msg)
+    p.metrics.ObserveProduce(msg.Topic, msg.Value.Length(), time.Since(start), err)
+    if err != nil {
+        return 0, 0, fmt.Errorf("failed to send message: %w", err)
+    }
+
+    span.SetAttributes(tracing.OffsetAttributes(partition, offset)...)
+    p.logSent(msg, partition, offset)
+    return partition, offset, nil
+}
+
 func (p *Producer) SendMessage(topic string, msg Message) error {
-    // Convert message value to JSON
-    valueBytes, err := json.Marshal(msg.Value)
+    return p.SendMessageContext(context.Background(), topic, msg)
+}
+
+// SendMessageContext sends msg in a span that continues the trace of ctx,
+// and injects the span's context into the message headers so consumers can
+// continue the trace.
+func (p *Producer) SendMessageContext(ctx context.Context, topic string, msg Message) error {
+    if p.client == nil {
+        return fmt.Errorf("SendMessage on async producer: %w", ErrUnsupportedMode)
+    }
+    producerMsg, err := p.newProducerMessage(ctx, topic, msg)
     if err != nil {
-        return fmt.Errorf("failed to marshal message value: %w", err)
+        return err
     }
 
-    // Convert headers
-    var headers []sarama.RecordHeader
-    for k, v := range msg.Headers {
-        headers = append(headers, sarama.RecordHeader{
-            Key:   []byte(k),
-            Value: []byte(v),
-        })
+    _, _, err = p.send(ctx, producerMsg)
+    return err
+}
+
+func (p *Producer) SendRawMessage(topic, key string, value []byte, headers map[string]string) error {
+    _, err := p.SendRecord(topic, Record{Key: key, Value: value, Headers: headers})
+    return err
+}
+
+// SendRecord sends a raw record and reports where it was written.
+func (p *Producer) SendRecord(topic string, record Record) (*Delivery, error) {
+    return p.SendRecordContext(context.Background(), topic, record)
+}
+
+// SendRecordContext is SendRecord with the trace propagation of
+// SendMessageContext.
+func (p *Producer) SendRecordContext(ctx context.Context, topic string, record Record) (*Delivery, error) {
+    if p.client == nil {
+        return nil, fmt.Errorf("SendRecord on async producer: %w", ErrUnsupportedMode)
     }
 
-    producerMsg := &sarama.ProducerMessage{
-        Topic:   topic,
-        Key:     sarama.StringEncoder(msg.Key),
-        Value:   sarama.ByteEncoder(valueBytes),
-        Headers: headers,
+    partition, offset, err := p.send(ctx, newRecordMessage(topic, record))
+    if err != nil {
+        return nil, err
     }
+    return &Delivery{
+        Topic:     topic,
+        Partition: partition,
+        Offset:    offset,
+        Key:       record.Key,
+    }, nil
+}
+
+func (p *Producer) Close() error {
+    p.unregister()
+    if p.asyncClient != nil {
+        err := p.asyncClient.Close()
+        <-p.done
+        return err
+    }
+    return p.client.Close()
+}
+
+// logSent logs a message acknowledged by the broker at debug level.
+func (p *Producer) logSent(msg *sarama.ProducerMessage, partition int32, offset int64) {
+    value, _ := msg.Value.Encode()
+    p.logger.Debug("Message sent",
+        slog.String("topic", msg.Topic),
+        slog.Int("partition", int(partition)),
+        slog.Int64("offset", offset),
+        slog.String("key", encoderString(msg.Key)),
+        p.payload().Attr(value))
+}
 
-    partition, offset, err := p.client.SendMessage(producerMsg)
+func (p *Producer) payload() logging.Payload {
+    return logging.Payload{Enabled: p.config.LogPayloads, MaxBytes: p.config.MaxLogPayload}
+}
+
+// newProducerMessage encodes the value of msg with the value serializer, or
+// as JSON without one.
+func (p *Producer) newProducerMessage(ctx context.Context, topic string, msg Message) (*sarama.ProducerMessage, error) {
+    var (
+        valueBytes []byte
+        err        error
+    )
+    if p.config.ValueSerializer != nil {
+        valueBytes, err = p.config.ValueSerializer.Serialize(ctx, topic, msg.Value)
+    } else {
+        valueBytes, err = json.Marshal(msg.Value)
+    }
     if err != nil {
-        return fmt.Errorf("failed to send message: %w", err)
+        return nil, fmt.Errorf("failed to marshal message value: %w", err)
     }
 
-    log.Printf("Message sent to %s[%d]@%d: %s", topic, partition, offset, string(valueBytes))
-    return nil
+    return &sarama.ProducerMessage{
+        Topic:   topic,
+        Key:     sarama.StringEncoder(msg.Key),
+        Value:   sarama.ByteEncoder(valueBytes),
+        Headers: recordHeaders(msg.Headers),
+    }, nil
 }
 
-func (p *Producer) SendRawMessage(topic, key string, value []byte, headers map[string]string) error {
-    // Convert headers
+func newRecordMessage(topic string, record Record) *sarama.ProducerMessage {
+    return &sarama.ProducerMessage{
+        Topic:     topic,
+        Key:       sarama.StringEncoder(record.Key),
+        Value:     sarama.ByteEncoder(record.Value),
+        Headers:   recordHeaders(record.Headers),
+        Partition: record.Partition,
+    }
+}
+
+func recordHeaders(headers map[string]string) []sarama.RecordHeader {
     var recordHeaders []sarama.RecordHeader
     for k, v := range headers {
         recordHeaders = append(recordHeaders, sarama.RecordHeader{
@@ -85,23 +335,59 @@ func (p *Producer) SendRawMessage(topic, key string, value []byte, headers map[s
             Value: []byte(v),
         })
     }
+    return recordHeaders
+}
 
-    producerMsg := &sarama.ProducerMessage{
-        Topic:   topic,
-        Key:     sarama.StringEncoder(key),
-        Value:   sarama.ByteEncoder(value),
-        Headers: recordHeaders,
+func encoderString(e sarama.Encoder) string {
+    if e == nil {
+        return ""
     }
-
-    partition, offset, err := p.client.SendMessage(producerMsg)
+    b, err := e.Encode()
     if err != nil {
-        return fmt.Errorf("failed to send message: %w", err)
+        return ""
+    }
+    return string(b)
+}
+
+// inflight counts messages handed to the async producer that have not been
+// acknowledged yet.
+type inflight struct {
+    mu   sync.Mutex
+    n    int
+    idle chan struct{}
+}
+
+func (f *inflight) add() {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if f.n == 0 {
+        f.idle = make(chan struct{})
     }
+    f.n++
+}
 
-    log.Printf("Raw message sent to %s[%d]@%d: %s", topic, partition, offset, string(value))
-    return nil
+func (f *inflight) done() {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.n--
+    if f.n == 0 {
+        close(f.idle)
+    }
 }
 
-func (p *Producer) Close() error {
-    return p.client.Close()
-}
\ No newline at end of file
+func (f *inflight) wait(ctx context.Context) error {
+    f.mu.Lock()
+    if f.n == 0 {
+        f.mu.Unlock()
+        return nil
+    }
+    idle := f.idle
+    f.mu.Unlock()
+
+    select {
+    case <-idle:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
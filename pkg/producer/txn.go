@@ -0,0 +1,107 @@
+package producer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrNotTransactional is returned by the transaction API when the producer was
+// created without Config.TransactionalID.
+var ErrNotTransactional = errors.New("producer is not transactional")
+
+// txnProducer is the transaction API shared by sarama's sync and async
+// producers.
+type txnProducer interface {
+    TxnStatus() sarama.ProducerTxnStatusFlag
+    IsTransactional() bool
+    BeginTxn() error
+    CommitTxn() error
+    AbortTxn() error
+    AddOffsetsToTxn(offsets map[string][]*sarama.PartitionOffsetMetadata, groupId string) error
+    AddMessageToTxn(msg *sarama.ConsumerMessage, groupId string, metadata *string) error
+}
+
+func (p *Producer) txn() (txnProducer, error) {
+    var client txnProducer = p.client
+    if p.asyncClient != nil {
+        client = p.asyncClient
+    }
+    if !client.IsTransactional() {
+        return nil, ErrNotTransactional
+    }
+    return client, nil
+}
+
+// BeginTxn starts a transaction. Every message sent until CommitTxn or
+// AbortTxn is part of it.
+func (p *Producer) BeginTxn() error {
+    client, err := p.txn()
+    if err != nil {
+        return err
+    }
+    if err := client.BeginTxn(); err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    return nil
+}
+
+// CommitTxn commits the current transaction.
+func (p *Producer) CommitTxn() error {
+    client, err := p.txn()
+    if err != nil {
+        return err
+    }
+    if err := client.CommitTxn(); err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+    return nil
+}
+
+// AbortTxn aborts the current transaction.
+func (p *Producer) AbortTxn() error {
+    client, err := p.txn()
+    if err != nil {
+        return err
+    }
+    if err := client.AbortTxn(); err != nil {
+        return fmt.Errorf("failed to abort transaction: %w", err)
+    }
+    return nil
+}
+
+// AddOffsetsToTxn commits consumer group offsets as part of the current
+// transaction.
+func (p *Producer) AddOffsetsToTxn(offsets map[string][]*sarama.PartitionOffsetMetadata, groupID string) error {
+    client, err := p.txn()
+    if err != nil {
+        return err
+    }
+    if err := client.AddOffsetsToTxn(offsets, groupID); err != nil {
+        return fmt.Errorf("failed to add offsets to transaction: %w", err)
+    }
+    return nil
+}
+
+// AddMessageToTxn commits the offset following msg as part of the current
+// transaction.
+func (p *Producer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupID string) error {
+    client, err := p.txn()
+    if err != nil {
+        return err
+    }
+    if err := client.AddMessageToTxn(msg, groupID, nil); err != nil {
+        return fmt.Errorf("failed to add message offset to transaction: %w", err)
+    }
+    return nil
+}
+
+// TxnStatus returns the state of the current transaction.
+func (p *Producer) TxnStatus() sarama.ProducerTxnStatusFlag {
+    client, err := p.txn()
+    if err != nil {
+        return 0
+    }
+    return client.TxnStatus()
+}
@@ -0,0 +1,186 @@
+package serde
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+)
+
+// AvroSerializerConfig describes how values are written.
+type AvroSerializerConfig struct {
+    Registry Registry
+    // Schema is the Avro schema, in JSON, values are written with. Values
+    // are map[string]any or structs with `avro` field tags.
+    Schema string
+    // SubjectStrategy names the subject of Schema. Defaults to
+    // TopicNameStrategy.
+    SubjectStrategy SubjectStrategy
+    // UseLatest writes with the subject's latest schema instead of
+    // registering Schema, for registries producers may not write to. Values
+    // are encoded with the latest schema, so they must match it.
+    UseLatest bool
+}
+
+// AvroSerializer encodes values as Avro in the Confluent wire format.
+type AvroSerializer struct {
+    config     AvroSerializerConfig
+    schema     avro.Schema
+    recordName string
+
+    mu      sync.Mutex
+    schemas map[string]writerSchema
+}
+
+// writerSchema is the schema values of a subject are written with.
+type writerSchema struct {
+    id     int
+    schema avro.Schema
+}
+
+func NewAvroSerializer(config AvroSerializerConfig) (*AvroSerializer, error) {
+    if config.Registry == nil {
+        return nil, fmt.Errorf("schema registry is required")
+    }
+    schema, err := parseAvro(config.Schema)
+    if err != nil {
+        return nil, err
+    }
+    if config.SubjectStrategy == nil {
+        config.SubjectStrategy = TopicNameStrategy
+    }
+
+    s := &AvroSerializer{config: config, schema: schema, schemas: map[string]writerSchema{}}
+    if named, ok := schema.(avro.NamedSchema); ok {
+        s.recordName = named.FullName()
+    }
+    return s, nil
+}
+
+// Serialize implements Serializer
+func (s *AvroSerializer) Serialize(ctx context.Context, topic string, value any) ([]byte, error) {
+    subject, err := s.config.SubjectStrategy(topic, s.recordName)
+    if err != nil {
+        return nil, err
+    }
+    writer, err := s.writerSchema(ctx, subject)
+    if err != nil {
+        return nil, err
+    }
+    payload, err := avro.Marshal(writer.schema, value)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode Avro value: %w", err)
+    }
+    return append(AppendHeader(make([]byte, 0, headerSize+len(payload)), writer.id), payload...), nil
+}
+
+// writerSchema registers or looks up the schema of subject once and caches
+// it with its ID. With UseLatest the latest schema is parsed, so the
+// payload always matches the ID in the header.
+func (s *AvroSerializer) writerSchema(ctx context.Context, subject string) (writerSchema, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if writer, ok := s.schemas[subject]; ok {
+        return writer, nil
+    }
+    writer := writerSchema{schema: s.schema}
+    if s.config.UseLatest {
+        id, text, err := s.config.Registry.Latest(ctx, subject)
+        if err != nil {
+            return writerSchema{}, err
+        }
+        schema, err := parseAvro(text)
+        if err != nil {
+            return writerSchema{}, fmt.Errorf("latest schema of %s: %w", subject, err)
+        }
+        writer = writerSchema{id: id, schema: schema}
+    } else {
+        id, err := s.config.Registry.Register(ctx, subject, s.config.Schema)
+        if err != nil {
+            return writerSchema{}, err
+        }
+        writer.id = id
+    }
+    s.schemas[subject] = writer
+    return writer, nil
+}
+
+// AvroDeserializerConfig describes how values are read.
+type AvroDeserializerConfig struct {
+    Registry Registry
+    // New returns the pointer each value is decoded into, such as a
+    // generated struct. Without it values decode to map[string]any.
+    New func() any
+}
+
+// AvroDeserializer decodes Avro values in the Confluent wire format with
+// the schema they were written with.
+type AvroDeserializer struct {
+    config AvroDeserializerConfig
+
+    mu      sync.Mutex
+    schemas map[int]avro.Schema
+}
+
+func NewAvroDeserializer(config AvroDeserializerConfig) (*AvroDeserializer, error) {
+    if config.Registry == nil {
+        return nil, fmt.Errorf("schema registry is required")
+    }
+    return &AvroDeserializer{config: config, schemas: map[int]avro.Schema{}}, nil
+}
+
+// Deserialize implements Deserializer
+func (d *AvroDeserializer) Deserialize(ctx context.Context, topic string, data []byte) (any, error) {
+    id, payload, err := SplitHeader(data)
+    if err != nil {
+        return nil, err
+    }
+    schema, err := d.schema(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    if d.config.New != nil {
+        value := d.config.New()
+        if err := avro.Unmarshal(schema, payload, value); err != nil {
+            return nil, fmt.Errorf("failed to decode Avro value with schema %d: %w", id, err)
+        }
+        return value, nil
+    }
+    var value any
+    if err := avro.Unmarshal(schema, payload, &value); err != nil {
+        return nil, fmt.Errorf("failed to decode Avro value with schema %d: %w", id, err)
+    }
+    return value, nil
+}
+
+func (d *AvroDeserializer) schema(ctx context.Context, id int) (avro.Schema, error) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+
+    if schema, ok := d.schemas[id]; ok {
+        return schema, nil
+    }
+    text, err := d.config.Registry.SchemaByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    schema, err := parseAvro(text)
+    if err != nil {
+        return nil, fmt.Errorf("schema %d: %w", id, err)
+    }
+    d.schemas[id] = schema
+    return schema, nil
+}
+
+// parseAvro parses schema with its own cache, so versions of a record that
+// share a name do not clash.
+func parseAvro(schema string) (avro.Schema, error) {
+    parsed, err := avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+    if err != nil {
+        return nil, fmt.Errorf("invalid Avro schema: %w", err)
+    }
+    return parsed, nil
+}
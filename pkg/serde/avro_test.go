@@ -0,0 +1,102 @@
+package serde
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const orderSchema = `{"type":"record","name":"Order","namespace":"shop","fields":[
+    {"name":"id","type":"string"},
+    {"name":"total","type":"double"}]}`
+
+const orderSchemaV2 = `{"type":"record","name":"Order","namespace":"shop","fields":[
+    {"name":"id","type":"string"},
+    {"name":"total","type":"double"},
+    {"name":"currency","type":"string","default":"EUR"}]}`
+
+func TestAvroRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    registry := NewMemoryRegistry()
+    serializer, err := NewAvroSerializer(AvroSerializerConfig{Registry: registry, Schema: orderSchema})
+    if err != nil {
+        t.Fatal(err)
+    }
+    deserializer, err := NewAvroDeserializer(AvroDeserializerConfig{Registry: registry})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    value := map[string]any{"id": "order-1", "total": 9.5}
+    data, err := serializer.Serialize(ctx, "orders", value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    id, _, err := SplitHeader(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if latest, _, err := registry.Latest(ctx, "orders-value"); err != nil || latest != id {
+        t.Errorf("registered ID = %d (%v), want %d", latest, err, id)
+    }
+
+    got, err := deserializer.Deserialize(ctx, "orders", data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, value) {
+        t.Errorf("value = %v, want %v", got, value)
+    }
+}
+
+func TestAvroUseLatest(t *testing.T) {
+    ctx := context.Background()
+    registry := NewMemoryRegistry()
+    if _, err := registry.Register(ctx, "orders-value", orderSchema); err != nil {
+        t.Fatal(err)
+    }
+    latestID, err := registry.Register(ctx, "orders-value", orderSchemaV2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    serializer, err := NewAvroSerializer(AvroSerializerConfig{Registry: registry, Schema: orderSchema, UseLatest: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, err := serializer.Serialize(ctx, "orders", map[string]any{"id": "order-1", "total": 9.5, "currency": "USD"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if id, _, _ := SplitHeader(data); id != latestID {
+        t.Errorf("schema ID = %d, want latest %d", id, latestID)
+    }
+
+    deserializer, err := NewAvroDeserializer(AvroDeserializerConfig{Registry: registry})
+    if err != nil {
+        t.Fatal(err)
+    }
+    got, err := deserializer.Deserialize(ctx, "orders", data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]any{"id": "order-1", "total": 9.5, "currency": "USD"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("value = %v, want %v", got, want)
+    }
+}
+
+func TestAvroUnnamedSchemaSubject(t *testing.T) {
+    serializer, err := NewAvroSerializer(AvroSerializerConfig{
+        Registry:        NewMemoryRegistry(),
+        Schema:          `"string"`,
+        SubjectStrategy: RecordNameStrategy,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := serializer.Serialize(context.Background(), "orders", "value"); !errors.Is(err, ErrUnnamedSchema) {
+        t.Errorf("err = %v, want %v", err, ErrUnnamedSchema)
+    }
+}
@@ -0,0 +1,69 @@
+package serde
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryRegistry is an in-memory Registry for tests and local development.
+// Identical schemas share an ID across subjects, as in the real registry.
+type MemoryRegistry struct {
+    mu       sync.Mutex
+    schemas  map[int]string
+    ids      map[string]int
+    subjects map[string][]int
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+    return &MemoryRegistry{
+        schemas:  map[int]string{},
+        ids:      map[string]int{},
+        subjects: map[string][]int{},
+    }
+}
+
+// Register implements Registry
+func (r *MemoryRegistry) Register(ctx context.Context, subject, schema string) (int, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    id, ok := r.ids[schema]
+    if !ok {
+        id = len(r.schemas) + 1
+        r.schemas[id] = schema
+        r.ids[schema] = id
+    }
+    for _, existing := range r.subjects[subject] {
+        if existing == id {
+            return id, nil
+        }
+    }
+    r.subjects[subject] = append(r.subjects[subject], id)
+    return id, nil
+}
+
+// Latest implements Registry
+func (r *MemoryRegistry) Latest(ctx context.Context, subject string) (int, string, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    versions := r.subjects[subject]
+    if len(versions) == 0 {
+        return 0, "", &RegistryError{StatusCode: 404, Code: 40401, Message: fmt.Sprintf("Subject '%s' not found.", subject)}
+    }
+    id := versions[len(versions)-1]
+    return id, r.schemas[id], nil
+}
+
+// SchemaByID implements Registry
+func (r *MemoryRegistry) SchemaByID(ctx context.Context, id int) (string, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    schema, ok := r.schemas[id]
+    if !ok {
+        return "", &RegistryError{StatusCode: 404, Code: 40403, Message: "Schema not found"}
+    }
+    return schema, nil
+}
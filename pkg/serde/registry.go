@@ -0,0 +1,190 @@
+package serde
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry stores schemas under subjects and assigns them IDs.
+type Registry interface {
+    // Register adds schema to subject, or finds it if it is already
+    // registered, and returns its ID.
+    Register(ctx context.Context, subject, schema string) (int, error)
+    // Latest returns the ID and the text of the latest schema of subject.
+    Latest(ctx context.Context, subject string) (int, string, error)
+    // SchemaByID returns the text of the schema with id.
+    SchemaByID(ctx context.Context, id int) (string, error)
+}
+
+// RegistryConfig describes a Confluent-compatible schema registry.
+type RegistryConfig struct {
+    // URL is the base URL, e.g. http://localhost:8081.
+    URL string
+    // Username and Password enable basic authentication.
+    Username string
+    Password string
+    // HTTPClient defaults to a client with a 10 second timeout.
+    HTTPClient *http.Client
+}
+
+// RegistryClient is a Registry over the schema registry REST API. Schema
+// IDs and texts are cached, since they never change once registered; the
+// latest version of a subject is always looked up.
+type RegistryClient struct {
+    config RegistryConfig
+    client *http.Client
+
+    mu      sync.RWMutex
+    schemas map[int]string
+    ids     map[subjectSchema]int
+}
+
+type subjectSchema struct {
+    subject string
+    schema  string
+}
+
+// RegistryError is an error response of the schema registry.
+type RegistryError struct {
+    StatusCode int
+    Code       int    `json:"error_code"`
+    Message    string `json:"message"`
+}
+
+func (e *RegistryError) Error() string {
+    return fmt.Sprintf("schema registry error %d (HTTP %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+func NewRegistryClient(config RegistryConfig) (*RegistryClient, error) {
+    if config.URL == "" {
+        return nil, fmt.Errorf("schema registry URL is required")
+    }
+    if _, err := url.Parse(config.URL); err != nil {
+        return nil, fmt.Errorf("invalid schema registry URL: %w", err)
+    }
+    client := config.HTTPClient
+    if client == nil {
+        client = &http.Client{Timeout: 10 * time.Second}
+    }
+    return &RegistryClient{
+        config:  config,
+        client:  client,
+        schemas: map[int]string{},
+        ids:     map[subjectSchema]int{},
+    }, nil
+}
+
+// Register implements Registry
+func (r *RegistryClient) Register(ctx context.Context, subject, schema string) (int, error) {
+    key := subjectSchema{subject, schema}
+    r.mu.RLock()
+    id, ok := r.ids[key]
+    r.mu.RUnlock()
+    if ok {
+        return id, nil
+    }
+
+    var resp struct {
+        ID int `json:"id"`
+    }
+    path := "/subjects/" + url.PathEscape(subject) + "/versions"
+    if err := r.do(ctx, http.MethodPost, path, map[string]string{"schema": schema}, &resp); err != nil {
+        return 0, fmt.Errorf("failed to register schema under %s: %w", subject, err)
+    }
+
+    r.mu.Lock()
+    r.ids[key] = resp.ID
+    r.schemas[resp.ID] = schema
+    r.mu.Unlock()
+    return resp.ID, nil
+}
+
+// Latest implements Registry
+func (r *RegistryClient) Latest(ctx context.Context, subject string) (int, string, error) {
+    var resp struct {
+        ID     int    `json:"id"`
+        Schema string `json:"schema"`
+    }
+    path := "/subjects/" + url.PathEscape(subject) + "/versions/latest"
+    if err := r.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+        return 0, "", fmt.Errorf("failed to get latest schema of %s: %w", subject, err)
+    }
+
+    r.mu.Lock()
+    r.schemas[resp.ID] = resp.Schema
+    r.mu.Unlock()
+    return resp.ID, resp.Schema, nil
+}
+
+// SchemaByID implements Registry
+func (r *RegistryClient) SchemaByID(ctx context.Context, id int) (string, error) {
+    r.mu.RLock()
+    schema, ok := r.schemas[id]
+    r.mu.RUnlock()
+    if ok {
+        return schema, nil
+    }
+
+    var resp struct {
+        Schema string `json:"schema"`
+    }
+    if err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+        return "", fmt.Errorf("failed to get schema %d: %w", id, err)
+    }
+
+    r.mu.Lock()
+    r.schemas[id] = resp.Schema
+    r.mu.Unlock()
+    return resp.Schema, nil
+}
+
+func (r *RegistryClient) do(ctx context.Context, method, path string, body, out any) error {
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.config.URL, "/")+path, reader)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", registryContentType)
+    if body != nil {
+        req.Header.Set("Content-Type", registryContentType)
+    }
+    if r.config.Username != "" {
+        req.SetBasicAuth(r.config.Username, r.config.Password)
+    }
+
+    resp, err := r.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        regErr := &RegistryError{StatusCode: resp.StatusCode}
+        if err := json.NewDecoder(resp.Body).Decode(regErr); err != nil || regErr.Message == "" {
+            regErr.Message = http.StatusText(resp.StatusCode)
+        }
+        return regErr
+    }
+    if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+        return fmt.Errorf("failed to decode schema registry response: %w", err)
+    }
+    return nil
+}
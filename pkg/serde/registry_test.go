@@ -0,0 +1,107 @@
+package serde
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// countingRegistry serves the few schema registry endpoints the client uses
+// and counts the requests to each path.
+type countingRegistry struct {
+    mu       sync.Mutex
+    requests map[string]int
+}
+
+func (c *countingRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    c.mu.Lock()
+    c.requests[r.Method+" "+r.URL.Path]++
+    c.mu.Unlock()
+
+    w.Header().Set("Content-Type", registryContentType)
+    switch r.Method + " " + r.URL.Path {
+    case "POST /subjects/orders-value/versions":
+        json.NewEncoder(w).Encode(map[string]any{"id": 7})
+    case "GET /subjects/orders-value/versions/latest":
+        json.NewEncoder(w).Encode(map[string]any{"id": 8, "schema": orderSchemaV2})
+    case "GET /schemas/ids/9":
+        json.NewEncoder(w).Encode(map[string]any{"schema": orderSchema})
+    default:
+        w.WriteHeader(http.StatusNotFound)
+        json.NewEncoder(w).Encode(map[string]any{"error_code": 40403, "message": "Schema not found"})
+    }
+}
+
+func (c *countingRegistry) count(method, path string) int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.requests[method+" "+path]
+}
+
+func TestRegistryClientCaching(t *testing.T) {
+    ctx := context.Background()
+    server := &countingRegistry{requests: map[string]int{}}
+    ts := httptest.NewServer(server)
+    defer ts.Close()
+
+    client, err := NewRegistryClient(RegistryConfig{URL: ts.URL + "/"})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for range 2 {
+        if id, err := client.Register(ctx, "orders-value", orderSchema); err != nil || id != 7 {
+            t.Fatalf("Register = %d, %v, want 7", id, err)
+        }
+        if schema, err := client.SchemaByID(ctx, 7); err != nil || schema != orderSchema {
+            t.Fatalf("SchemaByID(7) = %q, %v", schema, err)
+        }
+        if schema, err := client.SchemaByID(ctx, 9); err != nil || schema != orderSchema {
+            t.Fatalf("SchemaByID(9) = %q, %v", schema, err)
+        }
+        if id, _, err := client.Latest(ctx, "orders-value"); err != nil || id != 8 {
+            t.Fatalf("Latest = %d, %v, want 8", id, err)
+        }
+    }
+    if schema, err := client.SchemaByID(ctx, 8); err != nil || schema != orderSchemaV2 {
+        t.Fatalf("SchemaByID(8) = %q, %v", schema, err)
+    }
+
+    tests := []struct {
+        method, path string
+        want         int
+    }{
+        {http.MethodPost, "/subjects/orders-value/versions", 1},
+        {http.MethodGet, "/schemas/ids/7", 0},
+        {http.MethodGet, "/schemas/ids/8", 0},
+        {http.MethodGet, "/schemas/ids/9", 1},
+        {http.MethodGet, "/subjects/orders-value/versions/latest", 2},
+    }
+    for _, tt := range tests {
+        if got := server.count(tt.method, tt.path); got != tt.want {
+            t.Errorf("%s %s requests = %d, want %d", tt.method, tt.path, got, tt.want)
+        }
+    }
+}
+
+func TestRegistryClientError(t *testing.T) {
+    ts := httptest.NewServer(&countingRegistry{requests: map[string]int{}})
+    defer ts.Close()
+
+    client, err := NewRegistryClient(RegistryConfig{URL: ts.URL})
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, err = client.SchemaByID(context.Background(), 1)
+    var regErr *RegistryError
+    if !errors.As(err, &regErr) || regErr.StatusCode != http.StatusNotFound || regErr.Code != 40403 {
+        t.Errorf("err = %v, want a 404 RegistryError with code 40403", err)
+    }
+    if _, err := client.SchemaByID(context.Background(), 1); err == nil {
+        t.Error("a failed lookup was cached")
+    }
+}
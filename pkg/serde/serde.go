@@ -0,0 +1,92 @@
+// Package serde serializes message values with schemas kept in a
+// Confluent-compatible schema registry, using the Confluent wire format: a
+// zero magic byte and the 4-byte big-endian schema ID before the payload.
+package serde
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Serializer encodes message values for the producer.
+type Serializer interface {
+    Serialize(ctx context.Context, topic string, value any) ([]byte, error)
+}
+
+// Deserializer decodes message values for the consumer.
+type Deserializer interface {
+    Deserialize(ctx context.Context, topic string, data []byte) (any, error)
+}
+
+// magicByte starts every message in the Confluent wire format.
+const magicByte = 0
+
+// headerSize is the magic byte followed by the schema ID.
+const headerSize = 5
+
+// ErrNotWireFormat is returned for data that does not start with the
+// Confluent wire format header.
+var ErrNotWireFormat = errors.New("data is not in the schema registry wire format")
+
+// AppendHeader appends the wire format header for schema id to buf.
+func AppendHeader(buf []byte, id int) []byte {
+    buf = append(buf, magicByte)
+    return binary.BigEndian.AppendUint32(buf, uint32(id))
+}
+
+// SplitHeader returns the schema ID and the payload of data.
+func SplitHeader(data []byte) (int, []byte, error) {
+    if len(data) < headerSize || data[0] != magicByte {
+        return 0, nil, ErrNotWireFormat
+    }
+    return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], nil
+}
+
+// ErrUnnamedSchema is returned by the record name strategies for schemas
+// that are not named types, such as primitives and arrays.
+var ErrUnnamedSchema = errors.New("schema has no record name")
+
+// SubjectStrategy returns the registry subject of a value's schema, given
+// the topic and the schema's full record name, which is empty for unnamed
+// schemas.
+type SubjectStrategy func(topic, recordName string) (string, error)
+
+// TopicNameStrategy uses "<topic>-value", the registry default.
+func TopicNameStrategy(topic, recordName string) (string, error) {
+    return topic + "-value", nil
+}
+
+// RecordNameStrategy uses the record name, so one schema can be shared by
+// several topics.
+func RecordNameStrategy(topic, recordName string) (string, error) {
+    if recordName == "" {
+        return "", fmt.Errorf("record name strategy: %w", ErrUnnamedSchema)
+    }
+    return recordName, nil
+}
+
+// TopicRecordNameStrategy uses "<topic>-<record name>", so a topic can hold
+// several record types.
+func TopicRecordNameStrategy(topic, recordName string) (string, error) {
+    if recordName == "" {
+        return "", fmt.Errorf("topic record name strategy: %w", ErrUnnamedSchema)
+    }
+    return topic + "-" + recordName, nil
+}
+
+// ParseSubjectStrategy returns the strategy named "topic", "record" or
+// "topic-record". An empty name selects TopicNameStrategy.
+func ParseSubjectStrategy(name string) (SubjectStrategy, error) {
+    switch name {
+    case "", "topic":
+        return TopicNameStrategy, nil
+    case "record":
+        return RecordNameStrategy, nil
+    case "topic-record":
+        return TopicRecordNameStrategy, nil
+    default:
+        return nil, fmt.Errorf("unknown subject name strategy %q, expected topic, record or topic-record", name)
+    }
+}
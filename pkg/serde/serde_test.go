@@ -0,0 +1,67 @@
+package serde
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+    data := append(AppendHeader(nil, 258), "payload"...)
+    if want := []byte{0, 0, 0, 1, 2}; string(data[:headerSize]) != string(want) {
+        t.Fatalf("header = %v, want %v", data[:headerSize], want)
+    }
+
+    id, payload, err := SplitHeader(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if id != 258 || string(payload) != "payload" {
+        t.Errorf("SplitHeader = %d, %q, want 258, %q", id, payload, "payload")
+    }
+}
+
+func TestSplitHeaderInvalid(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+    }{
+        {"empty", nil},
+        {"short", []byte{0, 0, 1}},
+        {"wrong magic byte", []byte{1, 0, 0, 0, 1, 'x'}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, _, err := SplitHeader(tt.data); !errors.Is(err, ErrNotWireFormat) {
+                t.Errorf("err = %v, want %v", err, ErrNotWireFormat)
+            }
+        })
+    }
+}
+
+func TestSubjectStrategies(t *testing.T) {
+    tests := []struct {
+        name       string
+        strategy   SubjectStrategy
+        recordName string
+        want       string
+        wantErr    error
+    }{
+        {"topic", TopicNameStrategy, "shop.Order", "orders-value", nil},
+        {"topic unnamed", TopicNameStrategy, "", "orders-value", nil},
+        {"record", RecordNameStrategy, "shop.Order", "shop.Order", nil},
+        {"record unnamed", RecordNameStrategy, "", "", ErrUnnamedSchema},
+        {"topic record", TopicRecordNameStrategy, "shop.Order", "orders-shop.Order", nil},
+        {"topic record unnamed", TopicRecordNameStrategy, "", "", ErrUnnamedSchema},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := tt.strategy("orders", tt.recordName)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("err = %v, want %v", err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Errorf("subject = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
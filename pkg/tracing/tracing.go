@@ -0,0 +1,134 @@
+// Package tracing propagates OpenTelemetry trace context through Kafka
+// message headers. Exporters are left to the application: spans go to the
+// configured or global TracerProvider.
+package tracing
+
+import (
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans created by this
+// module.
+const TracerName = "github.com/radheem/ran-kafka-client-go"
+
+// Tracer returns the module's tracer from tp, or from the global provider
+// when tp is nil.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+    if tp == nil {
+        tp = otel.GetTracerProvider()
+    }
+    return tp.Tracer(TracerName)
+}
+
+// Propagator returns p, or the W3C trace context propagator (traceparent
+// and tracestate headers) when p is nil.
+func Propagator(p propagation.TextMapPropagator) propagation.TextMapPropagator {
+    if p == nil {
+        return propagation.TraceContext{}
+    }
+    return p
+}
+
+// MessageAttributes returns the messaging attributes describing a Kafka
+// message sent to or read from topic.
+func MessageAttributes(topic string, key []byte) []attribute.KeyValue {
+    attrs := []attribute.KeyValue{
+        attribute.String("messaging.system", "kafka"),
+        attribute.String("messaging.destination.name", topic),
+    }
+    if len(key) > 0 {
+        attrs = append(attrs, attribute.String("messaging.kafka.message.key", string(key)))
+    }
+    return attrs
+}
+
+// OffsetAttributes returns the attributes locating a message in its topic.
+func OffsetAttributes(partition int32, offset int64) []attribute.KeyValue {
+    return []attribute.KeyValue{
+        attribute.String("messaging.destination.partition.id", strconv.Itoa(int(partition))),
+        attribute.Int64("messaging.kafka.offset", offset),
+    }
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
+
+// ProducerHeaders adapts the headers of a message being produced to
+// propagation.TextMapCarrier.
+type ProducerHeaders struct {
+    Msg *sarama.ProducerMessage
+}
+
+// Get implements propagation.TextMapCarrier
+func (c ProducerHeaders) Get(key string) string {
+    for _, h := range c.Msg.Headers {
+        if string(h.Key) == key {
+            return string(h.Value)
+        }
+    }
+    return ""
+}
+
+// Set implements propagation.TextMapCarrier. An existing header is
+// replaced.
+func (c ProducerHeaders) Set(key, value string) {
+    for i, h := range c.Msg.Headers {
+        if string(h.Key) == key {
+            c.Msg.Headers[i].Value = []byte(value)
+            return
+        }
+    }
+    c.Msg.Headers = append(c.Msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys implements propagation.TextMapCarrier
+func (c ProducerHeaders) Keys() []string {
+    keys := make([]string, len(c.Msg.Headers))
+    for i, h := range c.Msg.Headers {
+        keys[i] = string(h.Key)
+    }
+    return keys
+}
+
+// ConsumerHeaders adapts the headers of a consumed message to
+// propagation.TextMapCarrier. Set is a no-op.
+type ConsumerHeaders struct {
+    Msg *sarama.ConsumerMessage
+}
+
+// Get implements propagation.TextMapCarrier
+func (c ConsumerHeaders) Get(key string) string {
+    for _, h := range c.Msg.Headers {
+        if h != nil && string(h.Key) == key {
+            return string(h.Value)
+        }
+    }
+    return ""
+}
+
+// Set implements propagation.TextMapCarrier
+func (c ConsumerHeaders) Set(key, value string) {}
+
+// Keys implements propagation.TextMapCarrier
+func (c ConsumerHeaders) Keys() []string {
+    keys := make([]string, 0, len(c.Msg.Headers))
+    for _, h := range c.Msg.Headers {
+        if h != nil {
+            keys = append(keys, string(h.Key))
+        }
+    }
+    return keys
+}